@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Action names a kind of change. Every change to tasks, their comments,
+// attachments, labels, people and links, and to projects is recorded. User
+// accounts and calendar feed tokens are credentials rather than data and are
+// not audited.
+type Action string
+
+const (
+	ActionTaskCreate       Action = "task.create"
+	ActionTaskUpdate       Action = "task.update"
+	ActionTaskDelete       Action = "task.delete"
+	ActionTaskRestore      Action = "task.restore"
+	ActionTaskPurge        Action = "task.purge"
+	ActionTaskTransition   Action = "task.transition"
+	ActionTaskMove         Action = "task.move"
+	ActionLabelAdd         Action = "task.label_add"
+	ActionLabelRemove      Action = "task.label_remove"
+	ActionAssigneeAdd      Action = "task.assignee_add"
+	ActionAssigneeRemove   Action = "task.assignee_remove"
+	ActionWatcherAdd       Action = "task.watcher_add"
+	ActionWatcherRemove    Action = "task.watcher_remove"
+	ActionAccessGrant      Action = "task.access_grant"
+	ActionAccessRevoke     Action = "task.access_revoke"
+	ActionDependencyAdd    Action = "task.dependency_add"
+	ActionDependencyRemove Action = "task.dependency_remove"
+	ActionCommentCreate    Action = "comment.create"
+	ActionCommentUpdate    Action = "comment.update"
+	ActionCommentDelete    Action = "comment.delete"
+	ActionAttachmentCreate Action = "attachment.create"
+	ActionAttachmentDelete Action = "attachment.delete"
+	ActionProjectCreate    Action = "project.create"
+	ActionProjectMemberSet Action = "project.member_set"
+)
+
+const (
+	TargetTask       = "task"
+	TargetComment    = "comment"
+	TargetAttachment = "attachment"
+	TargetProject    = "project"
+)
+
+// Meta describes who made a change and within which request. Actor 0 marks
+// changes made by the server itself, such as purging the trash.
+type Meta struct {
+	Actor     int
+	RequestID string
+}
+
+type Event struct {
+	ID         int64           `json:"id"`
+	Actor      int             `json:"actor"`
+	Action     Action          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   int             `json:"target_id"`
+	TaskID     *int            `json:"task_id,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
@@ -0,0 +1,105 @@
+package blob
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore() (*LocalStore, error) {
+	dir := os.Getenv("BLOB_DIR")
+	if dir == "" {
+		dir = "data/blobs"
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory %s: %v", dir, err)
+	}
+
+	return &LocalStore{dir: dir}, nil
+}
+
+func NewKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate blob key: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (ls *LocalStore) path(key string) (string, error) {
+	if key == "" || key != filepath.Base(key) || key == "." || key == ".." {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(ls.dir, key), nil
+}
+
+func (ls *LocalStore) Put(key string, r io.Reader) (int64, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(ls.dir, ".upload-*")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create blob %s: %v", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to write blob %s: %w", key, err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return 0, fmt.Errorf("failed to store blob %s: %v", key, err)
+	}
+
+	return n, nil
+}
+
+func (ls *LocalStore) Get(key string) (io.ReadCloser, error) {
+	path, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrBlobNotFound
+		}
+		return nil, fmt.Errorf("failed to open blob %s: %v", key, err)
+	}
+
+	return f, nil
+}
+
+func (ls *LocalStore) Delete(key string) error {
+	path, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(path); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrBlobNotFound
+		}
+		return fmt.Errorf("failed to delete blob %s: %v", key, err)
+	}
+
+	return nil
+}
@@ -45,10 +45,46 @@ func (rc *RedisCache) Set(t *task.Task) error {
 		return fmt.Errorf("task %d already exists in cache", t.ID)
 	}
 
+	projectID := ""
+	if t.ProjectID != nil {
+		projectID = strconv.Itoa(*t.ProjectID)
+	}
+
+	parentID := ""
+	if t.ParentID != nil {
+		parentID = strconv.Itoa(*t.ParentID)
+	}
+
+	dueAt := ""
+	if t.DueAt != nil {
+		dueAt = t.DueAt.Format(time.RFC3339Nano)
+	}
+
+	priority := ""
+	if t.Priority != nil {
+		priority = strconv.Itoa(*t.Priority)
+	}
+
+	labels, err := json.Marshal(t.Labels)
+	if err != nil {
+		return fmt.Errorf("failed to encode labels of task %d: %v", t.ID, err)
+	}
+
 	err = rc.cache.HSet(rc.ctx, id, map[string]interface{}{
-		"name":        t.Name,
-		"description": t.Description,
-		"comments":    t.Comments,
+		"name":          t.Name,
+		"description":   t.Description,
+		"owner":         t.Owner,
+		"project_id":    projectID,
+		"parent_id":     parentID,
+		"status":        string(t.Status),
+		"due_at":        dueAt,
+		"priority":      priority,
+		"labels":        labels,
+		"language":      t.Language,
+		"created_at":    t.CreatedAt.Format(time.RFC3339Nano),
+		"version":       t.Version,
+		"comment_count": t.CommentCount,
+		"comments":      t.Comments,
 	}).Err()
 	if err != nil {
 		return fmt.Errorf("failed to insert task %d into cache: %v", t.ID, err)
@@ -73,13 +109,69 @@ func (rc *RedisCache) Get(taskID int) (*task.Task, error) {
 		return nil, ErrTaskNotFound
 	}
 
+	owner, err := strconv.Atoi(data["owner"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse owner of task %d from cache: %v", taskID, err)
+	}
+
 	t := &task.Task{
 		ID:          taskID,
 		Name:        data["name"],
 		Description: data["description"],
+		Owner:       owner,
+		Status:      task.Status(data["status"]),
+		Language:    data["language"],
 		Comments:    json.RawMessage(data["comments"]),
 	}
 
+	if data["project_id"] != "" {
+		projectID, err := strconv.Atoi(data["project_id"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse project of task %d from cache: %v", taskID, err)
+		}
+		t.ProjectID = &projectID
+	}
+
+	if data["parent_id"] != "" {
+		parentID, err := strconv.Atoi(data["parent_id"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse parent of task %d from cache: %v", taskID, err)
+		}
+		t.ParentID = &parentID
+	}
+
+	if data["due_at"] != "" {
+		dueAt, err := time.Parse(time.RFC3339Nano, data["due_at"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse due date of task %d from cache: %v", taskID, err)
+		}
+		t.DueAt = &dueAt
+	}
+
+	if t.CreatedAt, err = time.Parse(time.RFC3339Nano, data["created_at"]); err != nil {
+		return nil, fmt.Errorf("failed to parse creation time of task %d from cache: %v", taskID, err)
+	}
+
+	if t.Version, err = strconv.Atoi(data["version"]); err != nil {
+		return nil, fmt.Errorf("failed to parse version of task %d from cache: %v", taskID, err)
+	}
+
+	if t.CommentCount, err = strconv.Atoi(data["comment_count"]); err != nil {
+		return nil, fmt.Errorf("failed to parse comment count of task %d from cache: %v", taskID, err)
+	}
+
+	if err = json.Unmarshal([]byte(data["labels"]), &t.Labels); err != nil {
+		return nil, fmt.Errorf("failed to parse labels of task %d from cache: %v", taskID, err)
+	}
+
+	if data["priority"] != "" {
+		priority, err := strconv.Atoi(data["priority"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse priority of task %d from cache: %v", taskID, err)
+		}
+		t.Priority = &priority
+	}
+
 	return t, nil
 }
 
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/project"
+	"restapi/task"
+
+	_ "github.com/lib/pq"
+)
+
+// visibleTaskCondition matches tasks of alias t that user $1 is allowed to read.
+// Assignees may read their tasks but need a grant or a role to edit them.
+// Trashed tasks are never visible.
+const visibleTaskCondition = `t.deleted_at is null and (t.owner = $1
+		or exists (select 1 from task_access a where a.task_id = t.id and a.user_id = $1)
+		or exists (select 1 from task_assignees ta where ta.task_id = t.id and ta.user_id = $1)
+		or exists (select 1 from project_members m where m.project_id = t.project_id and m.user_id = $1))`
+
+func (ps *PostgresStore) GetTaskAccess(taskID, userID int) (task.Access, error) {
+	var owner int
+	var granted, assigned bool
+	var role project.Role
+	query := `
+		select
+		    t.owner,
+		    exists (select 1 from task_access a where a.task_id = t.id and a.user_id = $2),
+		    exists (select 1 from task_assignees ta where ta.task_id = t.id and ta.user_id = $2),
+		    coalesce((select m.role from project_members m where m.project_id = t.project_id and m.user_id = $2), '')
+		from tasks t
+		where t.id = $1 and t.deleted_at is null
+	`
+
+	err := ps.db.QueryRow(query, taskID, userID).Scan(&owner, &granted, &assigned, &role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return task.AccessNone, ErrTaskNotFound
+		}
+		return task.AccessNone, fmt.Errorf("failed to check access to task %d: %v", taskID, err)
+	}
+
+	if owner == userID {
+		return task.AccessOwner, nil
+	}
+
+	access := role.Access()
+	if granted && access < task.AccessWrite {
+		access = task.AccessWrite
+	}
+	if assigned && access < task.AccessRead {
+		access = task.AccessRead
+	}
+	return access, nil
+}
+
+func (ps *PostgresStore) GrantTaskAccess(taskID, userID int, m audit.Meta) error {
+	var exists bool
+	query := "select exists (select 1 from users where id = $1)"
+	err := ps.db.QueryRow(query, userID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check if user %d exists: %v", userID, err)
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	query = "insert into task_access (task_id, user_id) values ($1, $2) on conflict do nothing"
+	_, err = ps.execTaskEvent(m, audit.ActionAccessGrant, taskID, nil, userSnapshot(userID), query, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to grant access to task %d for user %d: %v", taskID, userID, err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) RevokeTaskAccess(taskID, userID int, m audit.Meta) error {
+	query := "delete from task_access where task_id = $1 and user_id = $2"
+
+	revoked, err := ps.execTaskEvent(m, audit.ActionAccessRevoke, taskID, userSnapshot(userID), nil, query, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access to task %d for user %d: %v", taskID, userID, err)
+	}
+	if !revoked {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"restapi/audit"
+	"restapi/user"
+
+	"github.com/lib/pq"
+)
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+// userSnapshot is the audit snapshot of a user linked to a task.
+func userSnapshot(userID int) map[string]int {
+	return map[string]int{"user_id": userID}
+}
+
+func (ps *PostgresStore) AssignTask(taskID, userID int, m audit.Meta) error {
+	query := "insert into task_assignees (task_id, user_id) values ($1, $2) on conflict do nothing"
+	_, err := ps.execTaskEvent(m, audit.ActionAssigneeAdd, taskID, nil, userSnapshot(userID), query, taskID, userID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to assign user %d to task %d: %v", userID, taskID, err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) UnassignTask(taskID, userID int, m audit.Meta) error {
+	query := "delete from task_assignees where task_id = $1 and user_id = $2"
+
+	removed, err := ps.execTaskEvent(m, audit.ActionAssigneeRemove, taskID, userSnapshot(userID), nil, query, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to unassign user %d from task %d: %v", userID, taskID, err)
+	}
+	if !removed {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) GetAssignees(taskID int) ([]user.User, error) {
+	query := `
+		select u.id, u.login
+		from task_assignees ta
+		join users u on u.id = ta.user_id
+		where ta.task_id = $1
+		order by ta.assigned_at, u.id
+	`
+	return ps.selectUsers(query, taskID)
+}
+
+func (ps *PostgresStore) WatchTask(taskID, userID int, m audit.Meta) error {
+	query := "insert into task_watchers (task_id, user_id) values ($1, $2) on conflict do nothing"
+	_, err := ps.execTaskEvent(m, audit.ActionWatcherAdd, taskID, nil, userSnapshot(userID), query, taskID, userID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to add watcher %d to task %d: %v", userID, taskID, err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) UnwatchTask(taskID, userID int, m audit.Meta) error {
+	query := "delete from task_watchers where task_id = $1 and user_id = $2"
+
+	removed, err := ps.execTaskEvent(m, audit.ActionWatcherRemove, taskID, userSnapshot(userID), nil, query, taskID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove watcher %d from task %d: %v", userID, taskID, err)
+	}
+	if !removed {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) GetWatchers(taskID int) ([]user.User, error) {
+	query := `
+		select u.id, u.login
+		from task_watchers tw
+		join users u on u.id = tw.user_id
+		where tw.task_id = $1
+		order by u.login
+	`
+	return ps.selectUsers(query, taskID)
+}
+
+func (ps *PostgresStore) selectUsers(query string, args ...interface{}) ([]user.User, error) {
+	rows, err := ps.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select users from DB: %v", err)
+	}
+	defer rows.Close()
+
+	var users []user.User
+	for rows.Next() {
+		var u user.User
+		if err := rows.Scan(&u.ID, &u.Login); err != nil {
+			return nil, fmt.Errorf("failed to scan user %d: %v", len(users)+1, err)
+		}
+		users = append(users, u)
+	}
+
+	return users, nil
+}
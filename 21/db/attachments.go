@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+
+	_ "github.com/lib/pq"
+)
+
+const attachmentColumns = "id, task_id, uploader, filename, content_type, size, storage_key, created_at"
+
+func attachmentFields(a *task.Attachment) []interface{} {
+	return []interface{}{&a.ID, &a.TaskID, &a.Uploader, &a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt}
+}
+
+func (ps *PostgresStore) AddAttachment(a *task.Attachment, m audit.Meta) (*task.Attachment, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `insert into attachments (task_id, uploader, filename, content_type, size, storage_key)
+              values ($1, $2, $3, $4, $5, $6) returning ` + attachmentColumns
+
+	var inserted task.Attachment
+	err = tx.QueryRow(query, a.TaskID, a.Uploader, a.Filename, a.ContentType, a.Size, a.StorageKey).
+		Scan(attachmentFields(&inserted)...)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to insert attachment: %v", err)
+	}
+
+	err = insertEvent(tx, m, audit.ActionAttachmentCreate, audit.TargetAttachment, inserted.ID, &inserted.TaskID, nil, &inserted)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &inserted, nil
+}
+
+func (ps *PostgresStore) GetAttachment(taskID, attachmentID int) (*task.Attachment, error) {
+	query := "select " + attachmentColumns + " from attachments where id = $1 and task_id = $2"
+
+	var a task.Attachment
+	err := ps.db.QueryRow(query, attachmentID, taskID).Scan(attachmentFields(&a)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to select attachment %d from DB: %v", attachmentID, err)
+	}
+
+	return &a, nil
+}
+
+func (ps *PostgresStore) GetAttachments(taskID int) ([]task.Attachment, error) {
+	query := "select " + attachmentColumns + " from attachments where task_id = $1 order by created_at, id"
+
+	rows, err := ps.db.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select attachments of task %d: %v", taskID, err)
+	}
+	defer rows.Close()
+
+	attachments := []task.Attachment{}
+	for rows.Next() {
+		var a task.Attachment
+		if err := rows.Scan(attachmentFields(&a)...); err != nil {
+			return nil, fmt.Errorf("failed to scan attachment %d: %v", len(attachments)+1, err)
+		}
+		attachments = append(attachments, a)
+	}
+
+	return attachments, nil
+}
+
+func (ps *PostgresStore) DeleteAttachment(taskID, attachmentID int, m audit.Meta) (string, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := "delete from attachments where id = $1 and task_id = $2 returning " + attachmentColumns
+
+	var a task.Attachment
+	if err = tx.QueryRow(query, attachmentID, taskID).Scan(attachmentFields(&a)...); err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrAttachmentNotFound
+		}
+		return "", fmt.Errorf("failed to delete attachment %d: %v", attachmentID, err)
+	}
+
+	if err = insertEvent(tx, m, audit.ActionAttachmentDelete, audit.TargetAttachment, a.ID, &taskID, &a, nil); err != nil {
+		return "", err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return a.StorageKey, nil
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"restapi/audit"
+)
+
+const eventColumns = "id, actor, action, target_type, target_id, task_id, before, after, coalesce(request_id, ''), created_at"
+
+func eventFields(e *audit.Event) []interface{} {
+	return []interface{}{&e.ID, &e.Actor, &e.Action, &e.TargetType, &e.TargetID, &e.TaskID,
+		(*nullRawMessage)(&e.Before), (*nullRawMessage)(&e.After), &e.RequestID, &e.CreatedAt}
+}
+
+// nullRawMessage scans a nullable jsonb column, leaving the message nil on null.
+type nullRawMessage json.RawMessage
+
+func (m *nullRawMessage) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+	case []byte:
+		*m = append((*m)[:0], v...)
+	case string:
+		*m = nullRawMessage(v)
+	default:
+		return fmt.Errorf("unsupported type %T for json", src)
+	}
+	return nil
+}
+
+// insertEvent records a change within the transaction that makes it. A nil
+// before or after is stored as null.
+func insertEvent(tx *sql.Tx, m audit.Meta, action audit.Action, targetType string, targetID int, taskID *int,
+	before, after interface{}) error {
+	snapshots := make([]interface{}, 2)
+	for i, v := range []interface{}{before, after} {
+		if v == nil {
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode snapshot of %s %d: %v", targetType, targetID, err)
+		}
+		snapshots[i] = string(data)
+	}
+
+	query := `insert into audit_events (actor, action, target_type, target_id, task_id, before, after, request_id)
+              values ($1, $2, $3, $4, $5, $6, $7, nullif($8, ''))`
+	_, err := tx.Exec(query, m.Actor, action, targetType, targetID, taskID, snapshots[0], snapshots[1], m.RequestID)
+	if err != nil {
+		return fmt.Errorf("failed to insert %s event for %s %d: %v", action, targetType, targetID, err)
+	}
+	return nil
+}
+
+// execTaskEvent runs a statement that changes what hangs off a task, such as
+// its labels, people or links, and records the event when a row changed.
+func (ps *PostgresStore) execTaskEvent(m audit.Meta, action audit.Action, taskID int, before, after interface{},
+	query string, args ...interface{}) (bool, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err = insertEvent(tx, m, action, audit.TargetTask, taskID, &taskID, before, after); err != nil {
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return true, nil
+}
+
+// GetTaskEvents returns the events of the task newest first, starting below
+// the before ID when it is set.
+func (ps *PostgresStore) GetTaskEvents(taskID, limit int, before *int64) ([]audit.Event, error) {
+	query := `
+		select ` + eventColumns + `
+		from audit_events
+		where task_id = $1 and ($2::bigint is null or id < $2)
+		order by id desc
+		limit $3
+	`
+	return ps.selectEvents(query, taskID, before, limit)
+}
+
+// GetUserEvents returns the events made by the user newest first, starting
+// below the before ID when it is set.
+func (ps *PostgresStore) GetUserEvents(userID, limit int, before *int64) ([]audit.Event, error) {
+	query := `
+		select ` + eventColumns + `
+		from audit_events
+		where actor = $1 and ($2::bigint is null or id < $2)
+		order by id desc
+		limit $3
+	`
+	return ps.selectEvents(query, userID, before, limit)
+}
+
+func (ps *PostgresStore) selectEvents(query string, args ...interface{}) ([]audit.Event, error) {
+	rows, err := ps.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select events from DB: %v", err)
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+	for rows.Next() {
+		var e audit.Event
+		if err := rows.Scan(eventFields(&e)...); err != nil {
+			return nil, fmt.Errorf("failed to scan event %d: %v", len(events)+1, err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select events from DB: %v", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+)
+
+// RunBatch applies the operations in one transaction. With atomic set the
+// first failing operation rolls everything back and ErrBatchAborted is
+// returned along with the outcomes so far. Otherwise each operation runs in
+// its own savepoint, failed ones are undone and the rest are committed.
+func (ps *PostgresStore) RunBatch(ops []task.BatchOp, atomic bool, m audit.Meta) ([]task.BatchOutcome, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	outcomes := make([]task.BatchOutcome, 0, len(ops))
+	for _, op := range ops {
+		if !atomic {
+			if _, err = tx.Exec("savepoint batch_op"); err != nil {
+				return nil, fmt.Errorf("failed to create savepoint: %v", err)
+			}
+		}
+
+		var o task.BatchOutcome
+		switch op.Op {
+		case task.BatchCreate:
+			if o.Task, o.Err = addTask(tx, op.Task, op.Labels, m); o.Err == nil {
+				o.Touched = []int{o.Task.ID}
+			}
+		case task.BatchUpdate:
+			if o.Task, o.Err = patchTask(tx, op.ID, overwriteTask(op.Task), op.IfMatch, m); o.Err == nil {
+				o.Touched = []int{op.ID}
+			}
+		case task.BatchDelete:
+			o.Touched, o.Err = deleteTask(tx, op.ID, op.Mode, op.IfMatch, m)
+		default:
+			o.Err = fmt.Errorf("unknown batch operation %q", op.Op)
+		}
+		outcomes = append(outcomes, o)
+
+		if o.Err != nil {
+			if atomic {
+				return outcomes, ErrBatchAborted
+			}
+			if _, err = tx.Exec("rollback to savepoint batch_op"); err != nil {
+				return nil, fmt.Errorf("failed to roll back to savepoint: %v", err)
+			}
+			continue
+		}
+
+		if !atomic {
+			if _, err = tx.Exec("release savepoint batch_op"); err != nil {
+				return nil, fmt.Errorf("failed to release savepoint: %v", err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return outcomes, nil
+}
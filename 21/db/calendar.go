@@ -0,0 +1,55 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// SetCalendarToken replaces the calendar feed token of the user. Only its
+// hash is stored, so the token cannot be shown again.
+func (ps *PostgresStore) SetCalendarToken(userID int, token string) error {
+	query := `insert into calendar_tokens (user_id, token_hash) values ($1, $2)
+              on conflict (user_id) do update set token_hash = excluded.token_hash, created_at = now()`
+
+	if _, err := ps.db.Exec(query, userID, createHash(token)); err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to set calendar token of user %d: %v", userID, err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) DeleteCalendarToken(userID int) error {
+	res, err := ps.db.Exec("delete from calendar_tokens where user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete calendar token of user %d: %v", userID, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrCalendarTokenNotFound
+	}
+
+	return nil
+}
+
+// GetCalendarUser returns the user the calendar feed token belongs to.
+func (ps *PostgresStore) GetCalendarUser(token string) (int, error) {
+	var userID int
+	query := "select user_id from calendar_tokens where token_hash = $1"
+
+	err := ps.db.QueryRow(query, createHash(token)).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, ErrCalendarTokenNotFound
+		}
+		return 0, fmt.Errorf("failed to select calendar token: %v", err)
+	}
+
+	return userID, nil
+}
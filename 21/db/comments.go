@@ -0,0 +1,253 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+
+	_ "github.com/lib/pq"
+)
+
+const commentColumns = "id, task_id, parent_id, author, text, created_at, edited_at, deleted_at is not null"
+
+func commentFields(c *task.Comment) []interface{} {
+	return []interface{}{&c.ID, &c.TaskID, &c.ParentID, &c.Author, &c.Text, &c.CreatedAt, &c.EditedAt, &c.Deleted}
+}
+
+func (ps *PostgresStore) AddComment(taskID, author int, parentID *int, text string, m audit.Meta) (*task.Comment, error) {
+	if parentID != nil {
+		if _, err := ps.GetComment(taskID, *parentID); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `insert into comments (task_id, author, parent_id, text)
+              values ($1, $2, $3, $4) returning ` + commentColumns
+
+	var c task.Comment
+	err = tx.QueryRow(query, taskID, author, parentID, text).Scan(commentFields(&c)...)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to insert comment: %w", err)
+	}
+
+	if err = insertEvent(tx, m, audit.ActionCommentCreate, audit.TargetComment, c.ID, &taskID, nil, &c); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &c, nil
+}
+
+func (ps *PostgresStore) GetComment(taskID, commentID int) (*task.Comment, error) {
+	query := "select " + commentColumns + " from comments where id = $1 and task_id = $2"
+
+	var c task.Comment
+	err := ps.db.QueryRow(query, commentID, taskID).Scan(commentFields(&c)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to select comment %d from DB: %v", commentID, err)
+	}
+
+	return &c, nil
+}
+
+func (ps *PostgresStore) UpdateComment(commentID int, text string, m audit.Meta) (*task.Comment, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := lockComment(tx, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if before.Deleted {
+		return nil, ErrCommentNotFound
+	}
+
+	query := "update comments set text = $1, edited_at = now() where id = $2 returning " + commentColumns
+
+	var c task.Comment
+	if err = tx.QueryRow(query, text, commentID).Scan(commentFields(&c)...); err != nil {
+		return nil, fmt.Errorf("failed to update comment %d: %v", commentID, err)
+	}
+
+	if err = insertEvent(tx, m, audit.ActionCommentUpdate, audit.TargetComment, c.ID, &c.TaskID, before, &c); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &c, nil
+}
+
+// lockComment selects a comment for the rest of the transaction.
+func lockComment(tx *sql.Tx, commentID int) (*task.Comment, error) {
+	query := "select " + commentColumns + " from comments where id = $1 for update"
+
+	var c task.Comment
+	if err := tx.QueryRow(query, commentID).Scan(commentFields(&c)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to select comment %d from DB: %v", commentID, err)
+	}
+
+	return &c, nil
+}
+
+// DeleteComment removes a comment. A comment that already has replies keeps
+// its place in the thread and only loses its text, until its last reply is
+// removed.
+func (ps *PostgresStore) DeleteComment(commentID int, m audit.Meta) error {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	before, err := lockComment(tx, commentID)
+	if err != nil {
+		return err
+	}
+	if before.Deleted {
+		return ErrCommentNotFound
+	}
+
+	// after stays nil when the comment is gone for good.
+	var after *task.Comment
+	query := `update comments set text = '', deleted_at = now()
+              where id = $1 and exists (select 1 from comments r where r.parent_id = $1)
+              returning ` + commentColumns
+
+	var c task.Comment
+	err = tx.QueryRow(query, commentID).Scan(commentFields(&c)...)
+	switch {
+	case err == nil:
+		after = &c
+	case err == sql.ErrNoRows:
+		if _, err = tx.Exec("delete from comments where id = $1", commentID); err != nil {
+			return fmt.Errorf("failed to delete comment %d: %v", commentID, err)
+		}
+		if err = deleteEmptyPlaceholders(tx, before.ParentID, m); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("failed to delete comment %d: %v", commentID, err)
+	}
+
+	if err = insertEvent(tx, m, audit.ActionCommentDelete, audit.TargetComment, commentID, &before.TaskID, before, after); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// deleteEmptyPlaceholders removes the deleted comments left without replies,
+// walking up the thread from parentID.
+func deleteEmptyPlaceholders(tx *sql.Tx, parentID *int, m audit.Meta) error {
+	query := `delete from comments p
+              where p.id = $1 and p.deleted_at is not null
+                  and not exists (select 1 from comments r where r.parent_id = p.id)
+              returning ` + commentColumns
+
+	for parentID != nil {
+		var p task.Comment
+		err := tx.QueryRow(query, *parentID).Scan(commentFields(&p)...)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete comment %d: %v", *parentID, err)
+		}
+
+		if err = insertEvent(tx, m, audit.ActionCommentDelete, audit.TargetComment, p.ID, &p.TaskID, &p, nil); err != nil {
+			return err
+		}
+		parentID = p.ParentID
+	}
+	return nil
+}
+
+func latestCommentsColumn(limit string) string {
+	return `(
+		select coalesce(json_agg(json_build_object(
+		    'id', lc.id,
+		    'parent_id', lc.parent_id,
+		    'author', lc.author,
+		    'author_login', (select u.login from users u where u.id = lc.author),
+		    'text', lc.text,
+		    'created_at', lc.created_at,
+		    'edited_at', lc.edited_at,
+		    'deleted', lc.deleted_at is not null
+		) order by lc.created_at desc, lc.id desc), '[]'::json)
+		from (
+		    select * from comments
+		    where task_id = t.id
+		    order by created_at desc, id desc
+		    limit ` + limit + `
+		) lc
+	)`
+}
+
+func (ps *PostgresStore) GetComments(taskID, limit, offset int, desc bool) ([]task.Comment, int, error) {
+	var total int
+	query := "select count(*) from comments where task_id = $1"
+	if err := ps.db.QueryRow(query, taskID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments of task %d: %v", taskID, err)
+	}
+
+	order := "asc"
+	if desc {
+		order = "desc"
+	}
+
+	query = `
+		select c.id, c.task_id, c.parent_id, c.author, c.text, c.created_at, c.edited_at,
+		    c.deleted_at is not null, u.login
+		from comments c
+		join users u on u.id = c.author
+		where c.task_id = $1
+		order by c.created_at ` + order + `, c.id ` + order + `
+		limit $2 offset $3
+	`
+
+	rows, err := ps.db.Query(query, taskID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to select comments of task %d: %v", taskID, err)
+	}
+	defer rows.Close()
+
+	comments := []task.Comment{}
+	for rows.Next() {
+		var c task.Comment
+		if err := rows.Scan(append(commentFields(&c), &c.AuthorLogin)...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan comment %d: %v", len(comments)+1, err)
+		}
+		comments = append(comments, c)
+	}
+
+	return comments, total, nil
+}
@@ -0,0 +1,128 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"restapi/task"
+	"strconv"
+	"strings"
+)
+
+var sortColumns = map[string]string{
+	"id":            "int",
+	"name":          "text",
+	"description":   "text",
+	"status":        "text",
+	"due_at":        "timestamptz",
+	"created_at":    "timestamptz",
+	"priority":      "int",
+	"comment_count": "int",
+	"rank":          "real",
+}
+
+type cursor struct {
+	Sort     string    `json:"s"`
+	Values   []*string `json:"v"`
+	Backward bool      `json:"b,omitempty"`
+}
+
+func sortString(keys []task.SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+func (c *cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, keys []task.SortKey) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if c.Sort != sortString(keys) || len(c.Values) != len(keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// keysetCondition returns the condition selecting rows of alias s that come
+// after the cursor position in the order of keys, or before it when the
+// cursor points backward.
+func keysetCondition(c *cursor, keys []task.SortKey, args *[]interface{}) string {
+	var alternatives []string
+	var equal []string
+
+	for i, k := range keys {
+		col := "s." + k.Field
+		desc, nullsFirst := k.Desc, k.NullsFirst
+		if c.Backward {
+			desc, nullsFirst = !desc, !nullsFirst
+		}
+
+		var after, same string
+		if c.Values[i] == nil {
+			same = col + " is null"
+			if nullsFirst {
+				after = col + " is not null"
+			}
+		} else {
+			*args = append(*args, *c.Values[i])
+			v := "$" + strconv.Itoa(len(*args)) + "::" + sortColumns[k.Field]
+
+			op := ">"
+			if desc {
+				op = "<"
+			}
+			same = col + " = " + v
+			after = col + " " + op + " " + v
+			if !nullsFirst {
+				after = "(" + after + " or " + col + " is null)"
+			}
+		}
+
+		if after != "" {
+			alternatives = append(alternatives, "("+strings.Join(append(equal, after), " and ")+")")
+		}
+		equal = append(equal, same)
+	}
+
+	if len(alternatives) == 0 {
+		return "false"
+	}
+	return "(" + strings.Join(alternatives, " or ") + ")"
+}
+
+// orderClause returns the ORDER BY list for keys, reversed for backward paging.
+func orderClause(keys []task.SortKey, backward bool) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		desc, nullsFirst := k.Desc, k.NullsFirst
+		if backward {
+			desc, nullsFirst = !desc, !nullsFirst
+		}
+
+		parts[i] = "s." + k.Field
+		if desc {
+			parts[i] += " desc"
+		} else {
+			parts[i] += " asc"
+		}
+		if nullsFirst {
+			parts[i] += " nulls first"
+		} else {
+			parts[i] += " nulls last"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,176 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+
+	"github.com/lib/pq"
+)
+
+// dependencyLockKey serializes new dependencies so that two concurrent links
+// cannot close a cycle that neither of them sees on its own.
+const dependencyLockKey = 0x64657073
+
+func (ps *PostgresStore) AddDependency(taskID, blockerID int, m audit.Meta) (*task.Dependency, error) {
+	if taskID == blockerID {
+		return nil, task.ErrDependencyCycle
+	}
+
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("select pg_advisory_xact_lock($1)", dependencyLockKey); err != nil {
+		return nil, fmt.Errorf("failed to lock dependency graph: %v", err)
+	}
+
+	// The new edge closes a cycle if the blocker already waits on the task.
+	var cycle bool
+	query := `
+		with recursive blockers as (
+		    select blocker_id from task_dependencies where task_id = $1
+		    union
+		    select d.blocker_id
+		    from task_dependencies d
+		    join blockers b on d.task_id = b.blocker_id
+		)
+		select exists (select 1 from blockers where blocker_id = $2)
+	`
+	if err = tx.QueryRow(query, blockerID, taskID).Scan(&cycle); err != nil {
+		return nil, fmt.Errorf("failed to check blockers of task %d: %v", blockerID, err)
+	}
+	if cycle {
+		return nil, task.ErrDependencyCycle
+	}
+
+	query = "insert into task_dependencies (task_id, blocker_id) values ($1, $2) on conflict do nothing"
+	res, err := tx.Exec(query, taskID, blockerID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to insert dependency of task %d on %d: %v", taskID, blockerID, err)
+	}
+
+	d := task.Dependency{TaskID: taskID, BlockerID: blockerID}
+	query = "select created_at from task_dependencies where task_id = $1 and blocker_id = $2"
+	if err = tx.QueryRow(query, taskID, blockerID).Scan(&d.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to select dependency of task %d on %d: %v", taskID, blockerID, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected > 0 {
+		after := map[string]int{"blocker_id": blockerID}
+		if err = insertEvent(tx, m, audit.ActionDependencyAdd, audit.TargetTask, taskID, &taskID, nil, after); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &d, nil
+}
+
+func (ps *PostgresStore) RemoveDependency(taskID, blockerID int, m audit.Meta) error {
+	query := "delete from task_dependencies where task_id = $1 and blocker_id = $2"
+
+	before := map[string]int{"blocker_id": blockerID}
+	removed, err := ps.execTaskEvent(m, audit.ActionDependencyRemove, taskID, before, nil, query, taskID, blockerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete dependency of task %d on %d: %v", taskID, blockerID, err)
+	}
+	if !removed {
+		return ErrDependencyNotFound
+	}
+
+	return nil
+}
+
+// GetBlockers returns the visible tasks that block the task.
+func (ps *PostgresStore) GetBlockers(taskID, userID int) ([]task.Task, error) {
+	query := `
+		select ` + taskColumns + `
+		from task_dependencies d
+		join tasks t on t.id = d.blocker_id
+		where d.task_id = $2 and ` + visibleTaskCondition + `
+		order by t.id
+	`
+	return ps.selectTasks(query, userID, taskID)
+}
+
+// GetBlocked returns the visible tasks blocked by the task.
+func (ps *PostgresStore) GetBlocked(taskID, userID int) ([]task.Task, error) {
+	query := `
+		select ` + taskColumns + `
+		from task_dependencies d
+		join tasks t on t.id = d.task_id
+		where d.blocker_id = $2 and ` + visibleTaskCondition + `
+		order by t.id
+	`
+	return ps.selectTasks(query, userID, taskID)
+}
+
+// GetDependencies returns the dependencies between the given tasks.
+func (ps *PostgresStore) GetDependencies(taskIDs []int) ([]task.Dependency, error) {
+	query := `
+		select task_id, blocker_id, created_at
+		from task_dependencies
+		where task_id = any($1) and blocker_id = any($1)
+	`
+
+	rows, err := ps.db.Query(query, pq.Array(taskIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select dependencies: %v", err)
+	}
+	defer rows.Close()
+
+	var deps []task.Dependency
+	for rows.Next() {
+		var d task.Dependency
+		if err := rows.Scan(&d.TaskID, &d.BlockerID, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency %d: %v", len(deps)+1, err)
+		}
+		deps = append(deps, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select dependencies: %v", err)
+	}
+
+	return deps, nil
+}
+
+func (ps *PostgresStore) selectTasks(query string, args ...interface{}) ([]task.Task, error) {
+	rows, err := ps.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select tasks from DB: %v", err)
+	}
+	return scanTasks(rows)
+}
+
+// scanTasks reads rows of taskColumns and closes them.
+func scanTasks(rows *sql.Rows) ([]task.Task, error) {
+	defer rows.Close()
+
+	var tasks []task.Task
+	for rows.Next() {
+		var t task.Task
+		if err := rows.Scan(taskFields(&t)...); err != nil {
+			return nil, fmt.Errorf("failed to scan task %d: %v", len(tasks)+1, err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select tasks from DB: %v", err)
+	}
+
+	return tasks, nil
+}
@@ -3,7 +3,22 @@ package db
 import "errors"
 
 var (
-	ErrTaskNotFound      = errors.New("task not found")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrTaskNotFound          = errors.New("task not found")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrIncorrectPassword     = errors.New("incorrect password")
+	ErrProjectNotFound       = errors.New("project not found")
+	ErrProjectOwner          = errors.New("project owner role cannot be changed")
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrCommentNotFound       = errors.New("comment not found")
+	ErrLabelNotFound         = errors.New("label not found")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidOrder          = errors.New("invalid order")
+	ErrStatusConflict        = errors.New("task status was changed concurrently")
+	ErrTaskCycle             = errors.New("task cannot be moved under its own subtask")
+	ErrTaskHasSubtasks       = errors.New("task has subtasks")
+	ErrDependencyNotFound    = errors.New("dependency not found")
+	ErrRevisionNotFound      = errors.New("revision not found")
+	ErrVersionConflict       = errors.New("task version does not match")
+	ErrBatchAborted          = errors.New("batch aborted")
+	ErrCalendarTokenNotFound = errors.New("calendar token not found")
 )
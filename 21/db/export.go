@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"restapi/task"
+	"strconv"
+)
+
+const exportFetchSize = 500
+
+// StreamSelectedTasks reads the tasks matching f through a server-side cursor
+// and passes them to fn in batches of up to exportFetchSize, so the result is
+// never held in memory at once. At most f.Limit tasks are passed and the
+// returned flag reports whether more tasks matched. The query is cancelled
+// when ctx is done.
+func (ps *PostgresStore) StreamSelectedTasks(ctx context.Context, f *task.Filter, fn func([]task.Task) error) (bool, error) {
+	q, err := buildSelectedTasksQuery(f)
+	if err != nil {
+		return false, err
+	}
+
+	tx, err := ps.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, "declare export_tasks no scroll cursor for "+q.query, q.args...); err != nil {
+		return false, fmt.Errorf("failed to declare cursor: %v", err)
+	}
+
+	fetch := "fetch " + strconv.Itoa(exportFetchSize) + " from export_tasks"
+	batch := make([]task.Task, 0, exportFetchSize)
+	streamed := 0
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch tasks: %v", err)
+		}
+
+		fetched := 0
+		batch = batch[:0]
+		for rows.Next() {
+			fetched++
+			var t task.Task
+			if _, err := scanSelectedTask(rows, &t, len(q.keys)); err != nil {
+				rows.Close()
+				return false, fmt.Errorf("failed to scan task %d: %v", streamed+len(batch)+1, err)
+			}
+			batch = append(batch, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return false, fmt.Errorf("failed to fetch tasks: %v", err)
+		}
+
+		more := false
+		if f.Limit != nil && streamed+len(batch) > *f.Limit {
+			batch, more = batch[:*f.Limit-streamed], true
+		}
+		if len(batch) > 0 {
+			if err := fn(batch); err != nil {
+				return false, err
+			}
+			streamed += len(batch)
+		}
+		if more || fetched < exportFetchSize {
+			return more, nil
+		}
+	}
+}
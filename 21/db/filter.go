@@ -0,0 +1,80 @@
+package db
+
+import (
+	"fmt"
+	"restapi/filter"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var filterColumns = map[string]string{
+	"id":          "t.id",
+	"name":        "t.name",
+	"description": "t.description",
+	"status":      "t.status",
+	"owner":       "t.owner",
+	"project_id":  "t.project_id",
+	"parent_id":   "t.parent_id",
+	"priority":    "t.priority",
+	"due_at":      "t.due_at",
+}
+
+var filterTypes = map[filter.FieldType]string{
+	filter.TypeText: "text",
+	filter.TypeInt:  "int",
+	filter.TypeTime: "timestamptz",
+}
+
+func compileFilter(e filter.Expr, args *[]interface{}) (string, error) {
+	switch e := e.(type) {
+	case *filter.Group:
+		sep := " and "
+		if e.Or {
+			sep = " or "
+		}
+
+		parts := make([]string, len(e.Exprs))
+		for i, sub := range e.Exprs {
+			sql, err := compileFilter(sub, args)
+			if err != nil {
+				return "", err
+			}
+			parts[i] = sql
+		}
+		return "(" + strings.Join(parts, sep) + ")", nil
+
+	case *filter.Condition:
+		col, ok := filterColumns[e.Field]
+		if !ok {
+			return "", fmt.Errorf("unknown filter field %q", e.Field)
+		}
+		typ := filterTypes[filter.Fields[e.Field]]
+
+		if e.Op == filter.OpIn {
+			*args = append(*args, pq.Array(e.Values))
+			return col + " = any($" + strconv.Itoa(len(*args)) + "::" + typ + "[])", nil
+		}
+
+		*args = append(*args, e.Values[0])
+		v := "$" + strconv.Itoa(len(*args)) + "::" + typ
+
+		switch e.Op {
+		case filter.OpEq:
+			return col + " = " + v, nil
+		case filter.OpNe:
+			return col + " is distinct from " + v, nil
+		case filter.OpGt:
+			return col + " > " + v, nil
+		case filter.OpLt:
+			return col + " < " + v, nil
+		case filter.OpContains:
+			return "strpos(lower(" + col + "), lower(" + v + ")) > 0", nil
+		}
+		return "", fmt.Errorf("unknown filter operator %q", e.Op)
+
+	default:
+		return "", fmt.Errorf("unknown filter expression %T", e)
+	}
+}
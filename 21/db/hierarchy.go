@@ -0,0 +1,194 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+
+	"github.com/lib/pq"
+)
+
+// hierarchyLockKey serializes parent changes so that two concurrent moves
+// cannot close a cycle that neither of them sees on its own.
+const hierarchyLockKey = 0x7461736b
+
+func (ps *PostgresStore) GetSubtasks(taskID, userID int) ([]task.Task, error) {
+	query := `
+		select ` + taskColumns + `
+		from tasks t
+		where t.parent_id = $2 and ` + visibleTaskCondition + `
+		order by t.id
+	`
+	return ps.selectTasks(query, userID, taskID)
+}
+
+// GetSubtree returns all descendants of the task in depth-first order. Tasks
+// the user cannot see are skipped, but their own subtasks are still walked.
+func (ps *PostgresStore) GetSubtree(taskID, userID int) ([]task.Subtask, error) {
+	query := `
+		with recursive subtree as (
+		    select id, 1 as depth, array[id] as path
+		    from tasks
+		    where parent_id = $2
+		    union all
+		    select c.id, s.depth + 1, s.path || c.id
+		    from tasks c
+		    join subtree s on c.parent_id = s.id
+		    where not c.id = any(s.path)
+		)
+		select ` + taskColumns + `, s.depth
+		from subtree s
+		join tasks t on t.id = s.id
+		where ` + visibleTaskCondition + `
+		order by s.path
+	`
+
+	rows, err := ps.db.Query(query, userID, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select subtree of task %d: %v", taskID, err)
+	}
+	defer rows.Close()
+
+	var tasks []task.Subtask
+	for rows.Next() {
+		var t task.Subtask
+		if err := rows.Scan(append(taskFields(&t.Task), &t.Depth)...); err != nil {
+			return nil, fmt.Errorf("failed to scan subtask %d: %v", len(tasks)+1, err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select subtree of task %d: %v", taskID, err)
+	}
+
+	return tasks, nil
+}
+
+func (ps *PostgresStore) GetProgress(taskID int, closed []task.Status) (*task.Progress, error) {
+	statuses := make([]string, len(closed))
+	for i, s := range closed {
+		statuses[i] = string(s)
+	}
+
+	var self task.Status
+	p := task.Progress{TaskID: taskID}
+	query := `
+		with recursive subtree as (
+		    select id, status
+		    from tasks
+		    where parent_id = $1 and deleted_at is null
+		    union
+		    select c.id, c.status
+		    from tasks c
+		    join subtree s on c.parent_id = s.id
+		    where c.deleted_at is null
+		)
+		select t.status,
+		    (select count(*) from subtree),
+		    (select count(*) from subtree where status = any($2))
+		from tasks t
+		where t.id = $1 and t.deleted_at is null
+	`
+
+	err := ps.db.QueryRow(query, taskID, pq.Array(statuses)).Scan(&self, &p.Total, &p.Closed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to count progress of task %d: %v", taskID, err)
+	}
+
+	if p.Total == 0 {
+		p.Total = 1
+		for _, s := range closed {
+			if s == self {
+				p.Closed = 1
+			}
+		}
+	}
+	p.Percent = float64(p.Closed) * 100 / float64(p.Total)
+
+	return &p, nil
+}
+
+// MoveTask sets the parent of the task, or detaches it when parentID is nil.
+func (ps *PostgresStore) MoveTask(taskID int, parentID *int, m audit.Meta) (*task.Task, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("select pg_advisory_xact_lock($1)", hierarchyLockKey); err != nil {
+		return nil, fmt.Errorf("failed to lock task hierarchy: %v", err)
+	}
+
+	if parentID != nil {
+		var cycle bool
+		query := `
+			with recursive ancestors as (
+			    select id, parent_id from tasks where id = $1
+			    union
+			    select t.id, t.parent_id
+			    from tasks t
+			    join ancestors a on t.id = a.parent_id
+			)
+			select exists (select 1 from ancestors where id = $2)
+		`
+		if err = tx.QueryRow(query, *parentID, taskID).Scan(&cycle); err != nil {
+			return nil, fmt.Errorf("failed to check ancestors of task %d: %v", *parentID, err)
+		}
+		if cycle {
+			return nil, ErrTaskCycle
+		}
+	}
+
+	var oldParentID *int
+	query := "select parent_id from tasks where id = $1 and deleted_at is null"
+	if err = tx.QueryRow(query, taskID).Scan(&oldParentID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to select parent of task %d: %v", taskID, err)
+	}
+
+	var movedTask task.Task
+	query = "update tasks t set parent_id = $1 where id = $2 and deleted_at is null returning " + taskColumns
+	err = tx.QueryRow(query, parentID, taskID).Scan(taskFields(&movedTask)...)
+	if err != nil {
+		if err == sql.ErrNoRows || isForeignKeyViolation(err) {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to move task %d: %v", taskID, err)
+	}
+
+	before, after := map[string]*int{"parent_id": oldParentID}, map[string]*int{"parent_id": parentID}
+	if err = insertEvent(tx, m, audit.ActionTaskMove, audit.TargetTask, taskID, &taskID, before, after); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &movedTask, nil
+}
+
+// selectIDs appends the single int column returned by query to ids.
+func selectIDs(tx *sql.Tx, ids *[]int, query string, args ...interface{}) error {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		*ids = append(*ids, id)
+	}
+	return rows.Err()
+}
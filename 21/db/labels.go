@@ -0,0 +1,192 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+
+	"github.com/lib/pq"
+)
+
+const taskLabelsColumn = `coalesce((
+		select array_agg(l.name order by l.name)
+		from task_labels tl join labels l on l.id = tl.label_id
+		where tl.task_id = t.id
+	), '{}')`
+
+func uniqueLabels(labels []string) []string {
+	seen := make(map[string]bool, len(labels))
+	var unique []string
+	for _, l := range labels {
+		if !seen[l] {
+			seen[l] = true
+			unique = append(unique, l)
+		}
+	}
+	return unique
+}
+
+func (ps *PostgresStore) AddLabels(taskID int, labels []string, m audit.Meta) ([]string, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the task so concurrent additions agree on which labels are new.
+	if _, err = tx.Exec("select id from tasks where id = $1 for update", taskID); err != nil {
+		return nil, fmt.Errorf("failed to lock task %d: %v", taskID, err)
+	}
+
+	before, err := selectTaskLabels(tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = insertLabels(tx, taskID, labels); err != nil {
+		return nil, err
+	}
+
+	taskLabels, err := selectTaskLabels(tx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Labels the task already had change nothing, so its version stays.
+	if added := newLabels(before, taskLabels); len(added) > 0 {
+		if err = touchTask(tx, taskID); err != nil {
+			return nil, err
+		}
+
+		after := map[string][]string{"labels": added}
+		if err = insertEvent(tx, m, audit.ActionLabelAdd, audit.TargetTask, taskID, &taskID, nil, after); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return taskLabels, nil
+}
+
+func selectTaskLabels(tx *sql.Tx, taskID int) ([]string, error) {
+	var labels []string
+	query := "select " + taskLabelsColumn + " from tasks t where t.id = $1"
+	if err := tx.QueryRow(query, taskID).Scan(pq.Array(&labels)); err != nil {
+		return nil, fmt.Errorf("failed to select labels of task %d: %v", taskID, err)
+	}
+	return labels, nil
+}
+
+// newLabels returns the labels of after that are not in before.
+func newLabels(before, after []string) []string {
+	had := make(map[string]bool, len(before))
+	for _, l := range before {
+		had[l] = true
+	}
+	var added []string
+	for _, l := range after {
+		if !had[l] {
+			added = append(added, l)
+		}
+	}
+	return added
+}
+
+func insertLabels(tx *sql.Tx, taskID int, labels []string) error {
+	query := "insert into labels (name) select unnest($1::text[]) on conflict (name) do nothing"
+	if _, err := tx.Exec(query, pq.Array(labels)); err != nil {
+		return fmt.Errorf("failed to insert labels: %v", err)
+	}
+
+	query = `insert into task_labels (task_id, label_id)
+             select $1, id from labels where name = any($2)
+             on conflict do nothing`
+	if _, err := tx.Exec(query, taskID, pq.Array(labels)); err != nil {
+		return fmt.Errorf("failed to add labels to task %d: %v", taskID, err)
+	}
+
+	return nil
+}
+
+func (ps *PostgresStore) RemoveLabel(taskID int, label string, m audit.Meta) error {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `delete from task_labels tl using labels l
+              where tl.label_id = l.id and tl.task_id = $1 and l.name = $2`
+
+	res, err := tx.Exec(query, taskID, label)
+	if err != nil {
+		return fmt.Errorf("failed to remove label %s from task %d: %v", label, taskID, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrLabelNotFound
+	}
+
+	if err = touchTask(tx, taskID); err != nil {
+		return err
+	}
+
+	before := map[string][]string{"labels": {label}}
+	if err = insertEvent(tx, m, audit.ActionLabelRemove, audit.TargetTask, taskID, &taskID, before, nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return nil
+}
+
+// touchTask bumps the version of a task whose labels changed.
+func touchTask(tx *sql.Tx, taskID int) error {
+	if _, err := tx.Exec("update tasks set version = version + 1 where id = $1", taskID); err != nil {
+		return fmt.Errorf("failed to update version of task %d: %v", taskID, err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) GetLabels(userID int) ([]task.Label, error) {
+	query := `
+		select l.name, count(t.id)
+		from labels l
+		join task_labels tl on tl.label_id = l.id
+		join tasks t on t.id = tl.task_id
+		where ` + visibleTaskCondition + `
+		group by l.name
+		order by count(t.id) desc, l.name
+	`
+
+	rows, err := ps.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select labels from DB: %v", err)
+	}
+	defer rows.Close()
+
+	labels := []task.Label{}
+	for rows.Next() {
+		var l task.Label
+		if err := rows.Scan(&l.Name, &l.TaskCount); err != nil {
+			return nil, fmt.Errorf("failed to scan label %d: %v", len(labels)+1, err)
+		}
+		labels = append(labels, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select labels from DB: %v", err)
+	}
+
+	return labels, nil
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/project"
+
+	_ "github.com/lib/pq"
+)
+
+func (ps *PostgresStore) AddProject(p *project.Project, m audit.Meta) (*project.Project, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var insertedProject project.Project
+	query := "insert into projects (name, owner) values ($1, $2) returning id, name, owner, created_at"
+	err = tx.QueryRow(query, p.Name, p.Owner).
+		Scan(&insertedProject.ID, &insertedProject.Name, &insertedProject.Owner, &insertedProject.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert project: %v", err)
+	}
+
+	query = "insert into project_members (project_id, user_id, role) values ($1, $2, $3)"
+	if _, err = tx.Exec(query, insertedProject.ID, insertedProject.Owner, project.RoleOwner); err != nil {
+		return nil, fmt.Errorf("failed to add owner to project %d: %v", insertedProject.ID, err)
+	}
+
+	err = insertEvent(tx, m, audit.ActionProjectCreate, audit.TargetProject, insertedProject.ID, nil, nil, &insertedProject)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &insertedProject, nil
+}
+
+func (ps *PostgresStore) GetProjects(userID int) ([]project.Project, error) {
+	query := `
+		select p.id, p.name, p.owner, p.created_at
+		from projects p
+		join project_members m on m.project_id = p.id
+		where m.user_id = $1
+		order by p.id
+	`
+
+	rows, err := ps.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select projects from DB: %v", err)
+	}
+	defer rows.Close()
+
+	var projects []project.Project
+	for rows.Next() {
+		var p project.Project
+		if err := rows.Scan(&p.ID, &p.Name, &p.Owner, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan project %d: %v", len(projects)+1, err)
+		}
+		projects = append(projects, p)
+	}
+
+	return projects, nil
+}
+
+func (ps *PostgresStore) GetProjectRole(projectID, userID int) (project.Role, error) {
+	var role project.Role
+	query := `
+		select coalesce(m.role, '')
+		from projects p
+		left join project_members m on m.project_id = p.id and m.user_id = $2
+		where p.id = $1
+	`
+
+	err := ps.db.QueryRow(query, projectID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrProjectNotFound
+		}
+		return "", fmt.Errorf("failed to select role in project %d: %v", projectID, err)
+	}
+
+	return role, nil
+}
+
+func (ps *PostgresStore) AddProjectMember(projectID int, login string, role project.Role, am audit.Meta) (*project.Member, error) {
+	m := project.Member{
+		ProjectID: projectID,
+		Login:     login,
+	}
+
+	query := "select id from users where login = $1"
+	err := ps.db.QueryRow(query, login).Scan(&m.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to select user %s from DB: %v", login, err)
+	}
+
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// before stays nil when the user was not a member yet.
+	var before *project.Member
+	var oldRole project.Role
+	query = "select role from project_members where project_id = $1 and user_id = $2 for update"
+	err = tx.QueryRow(query, projectID, m.UserID).Scan(&oldRole)
+	switch {
+	case err == nil:
+		before = &project.Member{ProjectID: projectID, UserID: m.UserID, Login: login, Role: oldRole}
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("failed to select role of user %s in project %d: %v", login, projectID, err)
+	}
+
+	query = `insert into project_members (project_id, user_id, role) values ($1, $2, $3)
+             on conflict (project_id, user_id) do update set role = excluded.role
+             where project_members.role <> 'owner'
+             returning role`
+	err = tx.QueryRow(query, projectID, m.UserID, role).Scan(&m.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrProjectOwner
+		}
+		return nil, fmt.Errorf("failed to add user %s to project %d: %v", login, projectID, err)
+	}
+
+	if before == nil || before.Role != m.Role {
+		err = insertEvent(tx, am, audit.ActionProjectMemberSet, audit.TargetProject, projectID, nil, before, &m)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &m, nil
+}
+
+func (ps *PostgresStore) GetProjectMembers(projectID int) ([]project.Member, error) {
+	query := `
+		select m.project_id, m.user_id, u.login, m.role
+		from project_members m
+		join users u on u.id = m.user_id
+		where m.project_id = $1
+		order by u.login
+	`
+
+	rows, err := ps.db.Query(query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select members of project %d: %v", projectID, err)
+	}
+	defer rows.Close()
+
+	var members []project.Member
+	for rows.Next() {
+		var m project.Member
+		if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Login, &m.Role); err != nil {
+			return nil, fmt.Errorf("failed to scan member %d: %v", len(members)+1, err)
+		}
+		members = append(members, m)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+)
+
+const revisionColumns = "task_id, revision, name, coalesce(description, ''), author, created_at"
+
+func revisionFields(r *task.Revision) []interface{} {
+	return []interface{}{&r.TaskID, &r.Revision, &r.Name, &r.Description, &r.Author, &r.CreatedAt}
+}
+
+// insertRevision saves the current name and description of the task as its
+// next revision. The caller must hold the lock on the task row.
+func insertRevision(tx *sql.Tx, taskID int, name, description string, author int) error {
+	query := `insert into task_revisions (task_id, revision, name, description, author)
+              select $1, coalesce(max(revision), 0) + 1, $2, $3, $4
+              from task_revisions where task_id = $1`
+	if _, err := tx.Exec(query, taskID, name, description, author); err != nil {
+		return fmt.Errorf("failed to insert revision of task %d: %v", taskID, err)
+	}
+	return nil
+}
+
+// ensureBaseRevision saves t as the first revision of a task created before
+// revisions were kept.
+func ensureBaseRevision(tx *sql.Tx, t *task.Task) error {
+	query := `insert into task_revisions (task_id, revision, name, description, author)
+              select $1, 1, $2, $3, $4
+              where not exists (select 1 from task_revisions where task_id = $1)`
+	if _, err := tx.Exec(query, t.ID, t.Name, t.Description, t.Owner); err != nil {
+		return fmt.Errorf("failed to insert base revision of task %d: %v", t.ID, err)
+	}
+	return nil
+}
+
+func (ps *PostgresStore) GetRevisions(taskID int) ([]task.Revision, error) {
+	query := "select " + revisionColumns + " from task_revisions where task_id = $1 order by revision"
+
+	rows, err := ps.db.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select revisions of task %d: %v", taskID, err)
+	}
+	defer rows.Close()
+
+	var revisions []task.Revision
+	for rows.Next() {
+		var r task.Revision
+		if err := rows.Scan(revisionFields(&r)...); err != nil {
+			return nil, fmt.Errorf("failed to scan revision %d: %v", len(revisions)+1, err)
+		}
+		revisions = append(revisions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select revisions of task %d: %v", taskID, err)
+	}
+
+	return revisions, nil
+}
+
+func (ps *PostgresStore) GetRevision(taskID, revision int) (*task.Revision, error) {
+	query := "select " + revisionColumns + " from task_revisions where task_id = $1 and revision = $2"
+
+	var r task.Revision
+	if err := ps.db.QueryRow(query, taskID, revision).Scan(revisionFields(&r)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to select revision %d of task %d: %v", revision, taskID, err)
+	}
+
+	return &r, nil
+}
+
+// RevertTask restores the name and description of an earlier revision, which
+// is saved as a new revision.
+func (ps *PostgresStore) RevertTask(taskID, revision int, m audit.Meta) (*task.Task, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var oldTask task.Task
+	query := "select " + taskColumns + " from tasks t where t.id = $1 and t.deleted_at is null for update"
+	if err = tx.QueryRow(query, taskID).Scan(taskFields(&oldTask)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to lock task %d: %v", taskID, err)
+	}
+
+	var r task.Revision
+	query = "select " + revisionColumns + " from task_revisions where task_id = $1 and revision = $2"
+	if err = tx.QueryRow(query, taskID, revision).Scan(revisionFields(&r)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, fmt.Errorf("failed to select revision %d of task %d: %v", revision, taskID, err)
+	}
+
+	var updatedTask task.Task
+	query = "update tasks t set name = $1, description = $2 where id = $3 returning " + taskColumns
+	if err = tx.QueryRow(query, r.Name, r.Description, taskID).Scan(taskFields(&updatedTask)...); err != nil {
+		return nil, fmt.Errorf("failed to revert task %d: %v", taskID, err)
+	}
+
+	if err = insertRevision(tx, taskID, r.Name, r.Description, m.Actor); err != nil {
+		return nil, err
+	}
+
+	err = insertEvent(tx, m, audit.ActionTaskUpdate, audit.TargetTask, taskID, &taskID, &oldTask, &updatedTask)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &updatedTask, nil
+}
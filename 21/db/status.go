@@ -0,0 +1,93 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+
+	_ "github.com/lib/pq"
+)
+
+func (ps *PostgresStore) GetTaskStatus(taskID int) (task.Status, error) {
+	var status task.Status
+	query := "select status from tasks where id = $1"
+
+	err := ps.db.QueryRow(query, taskID).Scan(&status)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrTaskNotFound
+		}
+		return "", fmt.Errorf("failed to select status of task %d: %v", taskID, err)
+	}
+
+	return status, nil
+}
+
+func (ps *PostgresStore) TransitionTask(taskID, userID int, from, to task.Status, m audit.Meta) (*task.StatusChange, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := "update tasks set status = $1 where id = $2 and status = $3"
+	res, err := tx.Exec(query, to, taskID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update status of task %d: %v", taskID, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected == 0 {
+		return nil, ErrStatusConflict
+	}
+
+	var c task.StatusChange
+	query = `insert into task_status_history (task_id, from_status, to_status, changed_by)
+             values ($1, $2, $3, $4) returning id, task_id, from_status, to_status, changed_by, changed_at`
+	err = tx.QueryRow(query, taskID, from, to, userID).
+		Scan(&c.ID, &c.TaskID, &c.From, &c.To, &c.ChangedBy, &c.ChangedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert status change of task %d: %v", taskID, err)
+	}
+
+	before, after := map[string]task.Status{"status": from}, map[string]task.Status{"status": to}
+	if err = insertEvent(tx, m, audit.ActionTaskTransition, audit.TargetTask, taskID, &taskID, before, after); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return &c, nil
+}
+
+func (ps *PostgresStore) GetStatusHistory(taskID int) ([]task.StatusChange, error) {
+	query := `
+		select id, task_id, from_status, to_status, changed_by, changed_at
+		from task_status_history
+		where task_id = $1
+		order by changed_at, id
+	`
+
+	rows, err := ps.db.Query(query, taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select status history of task %d: %v", taskID, err)
+	}
+	defer rows.Close()
+
+	var history []task.StatusChange
+	for rows.Next() {
+		var c task.StatusChange
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.From, &c.To, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan status change %d: %v", len(history)+1, err)
+		}
+		history = append(history, c)
+	}
+
+	return history, nil
+}
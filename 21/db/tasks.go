@@ -3,45 +3,103 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"restapi/audit"
 	"restapi/task"
+	"sort"
 	"strconv"
 	"strings"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
-func (ps *PostgresStore) AddTask(t *task.Task) (*task.Task, error) {
+const taskColumns = `t.id, t.name, t.description, t.owner, t.project_id, t.parent_id, t.status, t.due_at, t.priority, t.language,
+	t.created_at, t.version, ` + taskLabelsColumn + ` as labels,
+	(select count(*) from comments cc where cc.task_id = t.id) as comment_count`
+
+const taskCommentsColumn = "comment_tree(t.id, null)"
+
+func taskFields(t *task.Task) []interface{} {
+	return []interface{}{
+		&t.ID, &t.Name, &t.Description, &t.Owner, &t.ProjectID, &t.ParentID, &t.Status, &t.DueAt, &t.Priority, &t.Language,
+		&t.CreatedAt, &t.Version, pq.Array(&t.Labels), &t.CommentCount,
+	}
+}
+
+func versionMatches(version int, ifMatch []int) bool {
+	if ifMatch == nil {
+		return true
+	}
+	for _, v := range ifMatch {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (ps *PostgresStore) AddTask(t *task.Task, m audit.Meta) (*task.Task, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	insertedTask, err := addTask(tx, t, nil, m)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return insertedTask, nil
+}
+
+// addTask inserts t with the given labels; t.Labels is ignored.
+func addTask(tx *sql.Tx, t *task.Task, labels []string, m audit.Meta) (*task.Task, error) {
 	var insertedTask task.Task
-	query := "insert into tasks (name, description) values ($1, $2) returning id, name, description"
-	err := ps.db.QueryRow(query, t.Name, t.Description).Scan(&insertedTask.ID, &insertedTask.Name, &insertedTask.Description)
+	query := `insert into tasks as t (name, description, owner, project_id, parent_id, status, due_at, priority, language)
+              values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+              returning ` + taskColumns
+	err := tx.QueryRow(query, t.Name, t.Description, t.Owner, t.ProjectID, t.ParentID, t.Status, t.DueAt, t.Priority, t.Language).
+		Scan(taskFields(&insertedTask)...)
 	if err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrTaskNotFound
+		}
 		return nil, fmt.Errorf("failed to insert task: %v", err)
 	}
+
+	if len(labels) > 0 {
+		if err = insertLabels(tx, insertedTask.ID, labels); err != nil {
+			return nil, err
+		}
+		insertedTask.Labels = uniqueLabels(labels)
+		sort.Strings(insertedTask.Labels)
+	}
+
+	if err = insertRevision(tx, insertedTask.ID, insertedTask.Name, insertedTask.Description, m.Actor); err != nil {
+		return nil, err
+	}
+
+	err = insertEvent(tx, m, audit.ActionTaskCreate, audit.TargetTask, insertedTask.ID, &insertedTask.ID, nil, &insertedTask)
+	if err != nil {
+		return nil, err
+	}
+
 	return &insertedTask, nil
 }
 
 func (ps *PostgresStore) GetTask(taskID int) (*task.Task, error) {
 	var t task.Task
 	query := `
-		select 
-		    t.id, t.name, t.description,
-		    coalesce(
-		        json_agg(
-		            json_build_object(
-		                'id', c.id,
-		                'author', c.author,
-		                'text', c.text,
-		                'created_at', c.created_at
-		            )
-		        ) filter (where c.id is not null), '[]'
-		    ) as comments
+		select ` + taskColumns + `, ` + taskCommentsColumn + ` as comments
 		from tasks t
-		left join comments c on c.task_id = t.id
-		where t.id = $1
-		group by t.id;
+		where t.id = $1 and t.deleted_at is null;
 	`
 
-	err := ps.db.QueryRow(query, taskID).Scan(&t.ID, &t.Name, &t.Description, &t.Comments)
+	err := ps.db.QueryRow(query, taskID).Scan(append(taskFields(&t), &t.Comments)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrTaskNotFound
@@ -51,115 +109,469 @@ func (ps *PostgresStore) GetTask(taskID int) (*task.Task, error) {
 	return &t, nil
 }
 
-func (ps *PostgresStore) GetSelectedTasks(name, orderBy, sort string, limit *int) ([]task.Task, error) {
-	query := `
-		SELECT 
-			t.id, t.name, t.description,
-			COALESCE(
-				json_agg(
-					json_build_object(
-						'id', c.id,
-						'author', c.author,
-						'text', c.text,
-						'created_at', c.created_at
-					)
-				) FILTER (WHERE c.id IS NOT NULL),
-				'[]'
-			) AS comments
+// selectedTasksQuery is the SQL for a task.Filter. Besides the task columns
+// it selects the values of the sort keys, which cursors are built from.
+type selectedTasksQuery struct {
+	query     string
+	args      []interface{}
+	where     string
+	whereArgs int
+	keys      []task.SortKey
+	cursor    *cursor
+}
+
+func buildSelectedTasksQuery(f *task.Filter) (*selectedTasksQuery, error) {
+	args := []interface{}{f.UserID}
+	where := " WHERE " + visibleTaskCondition
+
+	if f.Name != "" {
+		args = append(args, f.Name)
+		where += " and t.name = $" + strconv.Itoa(len(args))
+	}
+
+	if f.ProjectID != nil {
+		args = append(args, *f.ProjectID)
+		where += " and t.project_id = $" + strconv.Itoa(len(args))
+	}
+
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, s := range f.Statuses {
+			statuses[i] = string(s)
+		}
+		args = append(args, pq.Array(statuses))
+		where += " and t.status = any($" + strconv.Itoa(len(args)) + ")"
+	}
+
+	if f.AssigneeID != nil {
+		args = append(args, *f.AssigneeID)
+		where += " and exists (select 1 from task_assignees ta where ta.task_id = t.id and ta.user_id = $" +
+			strconv.Itoa(len(args)) + ")"
+	}
+
+	if f.DueBefore != nil {
+		args = append(args, *f.DueBefore)
+		where += " and t.due_at < $" + strconv.Itoa(len(args))
+	}
+
+	if f.DueAfter != nil {
+		args = append(args, *f.DueAfter)
+		where += " and t.due_at > $" + strconv.Itoa(len(args))
+	}
+
+	if f.HasDue {
+		where += " and t.due_at is not null"
+	}
+
+	if f.Overdue {
+		closed := make([]string, len(f.Closed))
+		for i, s := range f.Closed {
+			closed[i] = string(s)
+		}
+		args = append(args, pq.Array(closed))
+		where += " and t.due_at < now() and not (t.status = any($" + strconv.Itoa(len(args)) + "))"
+	}
+
+	if f.Ready {
+		closed := make([]string, len(f.Closed))
+		for i, s := range f.Closed {
+			closed[i] = string(s)
+		}
+		args = append(args, pq.Array(closed))
+		n := strconv.Itoa(len(args))
+		where += ` and not (t.status = any($` + n + `))
+			and not exists (select 1 from task_dependencies d join tasks b on b.id = d.blocker_id
+				where d.task_id = t.id and b.deleted_at is null and not (b.status = any($` + n + `)))`
+	}
+
+	if f.MinPriority != nil {
+		args = append(args, *f.MinPriority)
+		where += " and t.priority >= $" + strconv.Itoa(len(args))
+	}
+
+	if len(f.LabelsAny) > 0 {
+		args = append(args, pq.Array(f.LabelsAny))
+		where += ` and exists (select 1 from task_labels tl join labels l on l.id = tl.label_id
+			where tl.task_id = t.id and l.name = any($` + strconv.Itoa(len(args)) + `))`
+	}
+
+	if len(f.LabelsAll) > 0 {
+		labels := uniqueLabels(f.LabelsAll)
+		args = append(args, pq.Array(labels), len(labels))
+		where += ` and (select count(*) from task_labels tl join labels l on l.id = tl.label_id
+			where tl.task_id = t.id and l.name = any($` + strconv.Itoa(len(args)-1) + `)) = $` + strconv.Itoa(len(args))
+	}
+
+	if f.Expr != nil {
+		cond, err := compileFilter(f.Expr, &args)
+		if err != nil {
+			return nil, err
+		}
+		where += " and " + cond
+	}
+
+	rankColumn, snippetColumn := "null::real", "null::text"
+	if f.Search != "" {
+		args = append(args, f.SearchLanguage, f.Search)
+		lang := "$" + strconv.Itoa(len(args)-1) + "::regconfig"
+		q := "websearch_to_tsquery(" + lang + ", $" + strconv.Itoa(len(args)) + ")"
+
+		where += ` and (t.search_vector @@ ` + q + `
+			or exists (select 1 from comments sc where sc.task_id = t.id and sc.search_vector @@ ` + q + `))`
+		rankColumn = `ts_rank(t.search_vector, ` + q + `)
+			+ coalesce(max(ts_rank(c.search_vector, ` + q + `)), 0)`
+		snippetColumn = `ts_headline(` + lang + `,
+			t.name || ' ' || coalesce(t.description, '') || ' ' ||
+			coalesce(string_agg(c.text, ' ') filter (where c.search_vector @@ ` + q + `), ''),
+			` + q + `, 'MaxFragments=3, MaxWords=20, MinWords=5')`
+	}
+
+	whereArgs := len(args)
+
+	commentsColumn := taskCommentsColumn
+	if f.LatestComments != nil {
+		commentsColumn = "null::json"
+		if *f.LatestComments > 0 {
+			args = append(args, *f.LatestComments)
+			commentsColumn = latestCommentsColumn("$" + strconv.Itoa(len(args)))
+		}
+	}
+
+	inner := `
+		SELECT ` + taskColumns + `, ` + commentsColumn + ` AS comments,
+			` + rankColumn + ` AS rank, ` + snippetColumn + ` AS snippet
 		FROM tasks t
-		LEFT JOIN comments c ON c.task_id = t.id
 	`
-	var args []interface{}
-
-	if name != "" {
-		args = append(args, name)
-		query += " where name = $" + strconv.Itoa(len(args))
+	if f.Search != "" {
+		inner += " LEFT JOIN comments c ON c.task_id = t.id"
 	}
+	inner += where + " GROUP BY t.id"
 
-	query += " GROUP BY t.id, t.name, t.description"
+	keys := f.Sort
+	if len(keys) == 0 && f.Search != "" {
+		keys = []task.SortKey{{Field: "rank", Desc: true}}
+	}
+	hasID := false
+	for _, k := range keys {
+		if _, ok := sortColumns[k.Field]; !ok {
+			return nil, ErrInvalidOrder
+		}
+		hasID = hasID || k.Field == "id"
+	}
+	if !hasID {
+		keys = append(keys, task.SortKey{Field: "id"})
+	}
 
-	if orderBy != "" {
-		query += " order by " + orderBy
-		if strings.ToLower(sort) == "desc" {
-			query += " desc"
-		} else {
-			query += " asc"
+	var c *cursor
+	if f.Cursor != "" {
+		var err error
+		c, err = decodeCursor(f.Cursor, keys)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	if limit != nil {
-		args = append(args, *limit)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = "s." + k.Field + "::text"
+	}
+
+	query := "select s.*, " + strings.Join(values, ", ") + " from (" + inner + ") s"
+	if c != nil {
+		query += " where " + keysetCondition(c, keys, &args)
+	}
+
+	backward := c != nil && c.Backward
+	query += " order by " + orderClause(keys, backward)
+
+	if f.Limit != nil {
+		args = append(args, *f.Limit+1)
 		query += " limit $" + strconv.Itoa(len(args))
 	}
 
-	rows, err := ps.db.Query(query, args...)
+	return &selectedTasksQuery{
+		query:     query,
+		args:      args,
+		where:     where,
+		whereArgs: whereArgs,
+		keys:      keys,
+		cursor:    c,
+	}, nil
+}
+
+// scanSelectedTask scans a row of selectedTasksQuery into t and returns the
+// sort key values.
+func scanSelectedTask(rows *sql.Rows, t *task.Task, keys int) ([]*string, error) {
+	position := make([]*string, keys)
+	dest := append(taskFields(t), &t.Comments, &t.Rank, &t.Snippet)
+	for i := range position {
+		dest = append(dest, &position[i])
+	}
+	return position, rows.Scan(dest...)
+}
+
+func (ps *PostgresStore) GetSelectedTasks(f *task.Filter) (*task.Page, error) {
+	q, err := buildSelectedTasksQuery(f)
+	if err != nil {
+		return nil, err
+	}
+	keys, c := q.keys, q.cursor
+	backward := c != nil && c.Backward
+
+	page := &task.Page{}
+
+	if f.WithTotal {
+		var total int
+		query := "select count(*) from tasks t" + q.where
+		if err := ps.db.QueryRow(query, q.args[:q.whereArgs]...).Scan(&total); err != nil {
+			return nil, fmt.Errorf("failed to count tasks: %v", err)
+		}
+		page.Total = &total
+	}
+
+	rows, err := ps.db.Query(q.query, q.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to select tasks from DB: %v", err)
 	}
 	defer rows.Close()
 
 	var tasks []task.Task
+	var positions [][]*string
 	for rows.Next() {
 		var t task.Task
-		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.Comments); err != nil {
+		position, err := scanSelectedTask(rows, &t, len(keys))
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan task %d: %v", len(tasks)+1, err)
 		}
 		tasks = append(tasks, t)
+		positions = append(positions, position)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select tasks from DB: %v", err)
+	}
+
+	if f.Limit == nil {
+		page.Tasks = tasks
+		return page, nil
+	}
+
+	more := len(tasks) > *f.Limit
+	if more {
+		tasks, positions = tasks[:*f.Limit], positions[:*f.Limit]
+	}
+	if backward {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+			positions[i], positions[j] = positions[j], positions[i]
+		}
+	}
+	page.Tasks = tasks
+
+	if len(tasks) > 0 {
+		sort := sortString(keys)
+		if c != nil && (!backward || more) {
+			page.PrevCursor = (&cursor{Sort: sort, Values: positions[0], Backward: true}).encode()
+		}
+		if more || backward {
+			page.NextCursor = (&cursor{Sort: sort, Values: positions[len(positions)-1]}).encode()
+		}
+	}
+
+	return page, nil
+}
+
+// GetTasksStamp summarizes the tasks matching the filter, ignoring its sort,
+// cursor and limit. It reads only IDs and versions, so it is much cheaper
+// than GetSelectedTasks and suits checking whether a listing changed.
+func (ps *PostgresStore) GetTasksStamp(f *task.Filter) (*task.Stamp, error) {
+	q, err := buildSelectedTasksQuery(f)
+	if err != nil {
+		return nil, err
 	}
 
-	return tasks, nil
+	var s task.Stamp
+	query := `select count(*), md5(coalesce(string_agg(t.id || ':' || t.version, ',' order by t.id), ''))
+              from tasks t` + q.where
+	if err := ps.db.QueryRow(query, q.args[:q.whereArgs]...).Scan(&s.Count, &s.Digest); err != nil {
+		return nil, fmt.Errorf("failed to stamp tasks: %v", err)
+	}
+
+	return &s, nil
+}
+
+// UpdateTask overwrites the task. A non-nil ifMatch lists the versions the
+// task may currently have, otherwise ErrVersionConflict is returned.
+func (ps *PostgresStore) UpdateTask(t *task.Task, ifMatch []int, m audit.Meta) (*task.Task, error) {
+	return ps.PatchTask(t.ID, overwriteTask(t), ifMatch, m)
+}
+
+// overwriteTask replaces the editable fields of a task with those of t,
+// keeping the language when t has none.
+func overwriteTask(t *task.Task) func(*task.Task) error {
+	return func(cur *task.Task) error {
+		cur.Name, cur.Description, cur.DueAt, cur.Priority = t.Name, t.Description, t.DueAt, t.Priority
+		if t.Language != "" {
+			cur.Language = t.Language
+		}
+		return nil
+	}
 }
 
-func (ps *PostgresStore) UpdateTask(t *task.Task) (*task.Task, error) {
-	var exists bool
-	query := "select EXISTS (select 1 from tasks where id = $1)"
-	err := ps.db.QueryRow(query, t.ID).Scan(&exists)
+// PatchTask locks the task, lets apply change it and saves the result, all in
+// one transaction. Errors returned by apply are passed through unchanged.
+func (ps *PostgresStore) PatchTask(taskID int, apply func(*task.Task) error, ifMatch []int, m audit.Meta) (*task.Task, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	updatedTask, err := patchTask(tx, taskID, apply, ifMatch, m)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check if task %d exists: %v", t.ID, err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return updatedTask, nil
+}
+
+func patchTask(tx *sql.Tx, taskID int, apply func(*task.Task) error, ifMatch []int, m audit.Meta) (*task.Task, error) {
+	var oldTask task.Task
+	query := "select " + taskColumns + " from tasks t where t.id = $1 and t.deleted_at is null for update"
+	if err := tx.QueryRow(query, taskID).Scan(taskFields(&oldTask)...); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to lock task %d: %v", taskID, err)
 	}
-	if !exists {
-		return nil, ErrTaskNotFound
+	if !versionMatches(oldTask.Version, ifMatch) {
+		return nil, ErrVersionConflict
 	}
 
-	query = "update tasks set name = $1, description = $2 where id = $3 returning id, name, description"
+	t := oldTask
+	if err := apply(&t); err != nil {
+		return nil, err
+	}
+
+	query = `update tasks t set name = $1, description = $2, due_at = $3, priority = $4, language = $5::regconfig
+             where id = $6
+             returning ` + taskColumns
 	var updatedTask task.Task
 
-	err = ps.db.QueryRow(query, t.Name, t.Description, t.ID).Scan(&updatedTask.ID, &updatedTask.Name, &updatedTask.Description)
+	err := tx.QueryRow(query, t.Name, t.Description, t.DueAt, t.Priority, t.Language, taskID).
+		Scan(taskFields(&updatedTask)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update task %d: %v", taskID, err)
+	}
+
+	if updatedTask.Name != oldTask.Name || updatedTask.Description != oldTask.Description {
+		if err = ensureBaseRevision(tx, &oldTask); err != nil {
+			return nil, err
+		}
+		if err = insertRevision(tx, taskID, updatedTask.Name, updatedTask.Description, m.Actor); err != nil {
+			return nil, err
+		}
+	}
+
+	err = insertEvent(tx, m, audit.ActionTaskUpdate, audit.TargetTask, taskID, &taskID, &oldTask, &updatedTask)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update task %d: %v", t.ID, err)
+		return nil, err
 	}
+
 	return &updatedTask, nil
 }
 
-func (ps *PostgresStore) DeleteTask(taskID int) error {
-	query := "delete from tasks where id = $1"
-
-	res, err := ps.db.Exec(query, taskID)
+// DeleteTask moves the task and, depending on mode, its subtasks to the trash.
+// It returns the IDs of the trashed or detached tasks. A non-nil ifMatch is
+// checked against the version of the task as in UpdateTask.
+func (ps *PostgresStore) DeleteTask(taskID int, mode task.DeleteMode, ifMatch []int, m audit.Meta) ([]int, error) {
+	tx, err := ps.db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete task %d from DB: %v", taskID, err)
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := res.RowsAffected()
+	ids, err := deleteTask(tx, taskID, mode, ifMatch, m)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if rowsAffected == 0 {
-		return ErrTaskNotFound
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	return nil
+	return ids, nil
 }
 
-func (ps *PostgresStore) AddComment(taskID, author int, text string) (*task.Comment, error) {
-	query := `insert into comments (task_id, author, text) 
-              values ($1, $2, $3) returning id, task_id, author, text, created_at`
+func deleteTask(tx *sql.Tx, taskID int, mode task.DeleteMode, ifMatch []int, m audit.Meta) ([]int, error) {
+	var version int
+	query := "select version from tasks where id = $1 and deleted_at is null for update"
+	if err := tx.QueryRow(query, taskID).Scan(&version); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to lock task %d: %v", taskID, err)
+	}
+	if !versionMatches(version, ifMatch) {
+		return nil, ErrVersionConflict
+	}
+
+	deleted, detached := []int{taskID}, []int(nil)
+	switch mode {
+	case task.DeleteForbid:
+		var hasSubtasks bool
+		query = "select exists (select 1 from tasks where parent_id = $1 and deleted_at is null)"
+		if err := tx.QueryRow(query, taskID).Scan(&hasSubtasks); err != nil {
+			return nil, fmt.Errorf("failed to check subtasks of task %d: %v", taskID, err)
+		}
+		if hasSubtasks {
+			return nil, ErrTaskHasSubtasks
+		}
+	case task.DeleteOrphan:
+		query = "update tasks set parent_id = null where parent_id = $1 and deleted_at is null returning id"
+		if err := selectIDs(tx, &detached, query, taskID); err != nil {
+			return nil, fmt.Errorf("failed to detach subtasks of task %d: %v", taskID, err)
+		}
+	case task.DeleteCascade:
+		query = `
+			with recursive subtree as (
+			    select id from tasks where parent_id = $1 and deleted_at is null
+			    union
+			    select c.id from tasks c join subtree s on c.parent_id = s.id where c.deleted_at is null
+			)
+			select t.id from tasks t where t.id in (select id from subtree) for update
+		`
+		if err := selectIDs(tx, &deleted, query, taskID); err != nil {
+			return nil, fmt.Errorf("failed to lock subtasks of task %d: %v", taskID, err)
+		}
+	}
 
-	var c task.Comment
-	err := ps.db.QueryRow(query, taskID, author, text).
-		Scan(&c.ID, &c.TaskID, &c.Author, &c.Text, &c.CreatedAt)
+	query = "select " + taskColumns + " from tasks t where t.id = any($1) order by t.id"
+	rows, err := tx.Query(query, pq.Array(deleted))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select deleted tasks: %v", err)
+	}
+	snapshots, err := scanTasks(rows)
 	if err != nil {
-		return nil, fmt.Errorf("failed to insert comment: %w", err)
+		return nil, err
+	}
+
+	for i := range snapshots {
+		t := &snapshots[i]
+		if err = insertEvent(tx, m, audit.ActionTaskDelete, audit.TargetTask, t.ID, &t.ID, t, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// deleted_root remembers which deletion trashed a task, so restoring the
+	// root brings back the subtasks trashed with it.
+	query = "update tasks set deleted_at = now(), deleted_by = $2, deleted_root = $3 where id = any($1)"
+	if _, err = tx.Exec(query, pq.Array(deleted), m.Actor, taskID); err != nil {
+		return nil, fmt.Errorf("failed to delete task %d from DB: %v", taskID, err)
 	}
 
-	return &c, nil
+	return append(deleted, detached...), nil
 }
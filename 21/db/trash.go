@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"restapi/audit"
+	"restapi/task"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetTrash returns the trashed tasks owned by the user, newest first. Subtasks
+// trashed along with a task of the same owner are counted but not listed.
+// Subtasks trashed along with another user's task are listed on their own,
+// since their owner can only restore them one by one.
+func (ps *PostgresStore) GetTrash(userID int) ([]task.TrashedTask, error) {
+	query := `
+		select ` + taskColumns + `, t.deleted_at, t.deleted_by,
+		    case when t.deleted_root = t.id
+		        then (select count(*) from tasks s where s.deleted_root = t.id and s.id <> t.id)
+		        else 0
+		    end
+		from tasks t
+		where t.owner = $1 and t.deleted_at is not null
+		    and (t.deleted_root = t.id
+		        or not exists (select 1 from tasks r where r.id = t.deleted_root and r.owner = t.owner))
+		order by t.deleted_at desc, t.id
+	`
+
+	rows, err := ps.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select trash of user %d: %v", userID, err)
+	}
+	defer rows.Close()
+
+	var tasks []task.TrashedTask
+	for rows.Next() {
+		var t task.TrashedTask
+		if err := rows.Scan(append(taskFields(&t.Task), &t.DeletedAt, &t.DeletedBy, &t.Subtasks)...); err != nil {
+			return nil, fmt.Errorf("failed to scan trashed task %d: %v", len(tasks)+1, err)
+		}
+		tasks = append(tasks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to select trash of user %d: %v", userID, err)
+	}
+
+	return tasks, nil
+}
+
+// RestoreTask takes a trashed task of m.Actor out of the trash together with
+// the subtasks deleted with it. A restored task whose parent is still in the
+// trash becomes a top-level task.
+func (ps *PostgresStore) RestoreTask(taskID int, m audit.Meta) ([]task.Task, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	query := "select id from tasks where id = $1 and owner = $2 and deleted_at is not null for update"
+	if err = tx.QueryRow(query, taskID, m.Actor).Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to lock task %d: %v", taskID, err)
+	}
+
+	var restored []int
+	query = `update tasks set deleted_at = null, deleted_by = null, deleted_root = null
+             where (id = $1 or deleted_root = $1) and deleted_at is not null
+             returning id`
+	if err = selectIDs(tx, &restored, query, taskID); err != nil {
+		return nil, fmt.Errorf("failed to restore task %d: %v", taskID, err)
+	}
+
+	query = `update tasks c set parent_id = null
+             where c.id = any($1)
+                 and exists (select 1 from tasks p where p.id = c.parent_id and p.deleted_at is not null)`
+	if _, err = tx.Exec(query, pq.Array(restored)); err != nil {
+		return nil, fmt.Errorf("failed to detach restored tasks: %v", err)
+	}
+
+	query = "select " + taskColumns + " from tasks t where t.id = any($1) order by t.id"
+	rows, err := tx.Query(query, pq.Array(restored))
+	if err != nil {
+		return nil, fmt.Errorf("failed to select restored tasks: %v", err)
+	}
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tasks {
+		t := &tasks[i]
+		if err = insertEvent(tx, m, audit.ActionTaskRestore, audit.TargetTask, t.ID, &t.ID, nil, t); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return tasks, nil
+}
+
+// PurgeTasks permanently removes up to limit tasks trashed before the given
+// time. It returns the IDs of the removed tasks and the storage keys of their
+// attachments, whose blobs are left for the caller to remove.
+func (ps *PostgresStore) PurgeTasks(before time.Time, limit int) ([]int, []string, error) {
+	tx, err := ps.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var ids []int
+	query := `select id from tasks where deleted_at < $1
+              order by deleted_at, id
+              limit $2
+              for update skip locked`
+	if err = selectIDs(tx, &ids, query, before, limit); err != nil {
+		return nil, nil, fmt.Errorf("failed to select expired tasks: %v", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil, nil
+	}
+
+	var keys []string
+	query = "select coalesce(array_agg(storage_key), '{}') from attachments where task_id = any($1)"
+	if err = tx.QueryRow(query, pq.Array(ids)).Scan(pq.Array(&keys)); err != nil {
+		return nil, nil, fmt.Errorf("failed to select attachments of expired tasks: %v", err)
+	}
+
+	for _, id := range ids {
+		id := id
+		if err = insertEvent(tx, audit.Meta{}, audit.ActionTaskPurge, audit.TargetTask, id, &id, nil, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	query = "delete from tasks where id = any($1)"
+	if _, err = tx.Exec(query, pq.Array(ids)); err != nil {
+		return nil, nil, fmt.Errorf("failed to purge expired tasks: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+
+	return ids, keys, nil
+}
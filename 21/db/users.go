@@ -40,6 +40,21 @@ func (ps *PostgresStore) CheckUser(data *user.UserData) (int, error) {
 	return userID, nil
 }
 
+func (ps *PostgresStore) IsAdmin(userID int) (bool, error) {
+	var isAdmin bool
+	query := "select is_admin from users where id = $1"
+
+	err := ps.db.QueryRow(query, userID).Scan(&isAdmin)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, ErrUserNotFound
+		}
+		return false, fmt.Errorf("failed to select user %d from DB: %v", userID, err)
+	}
+
+	return isAdmin, nil
+}
+
 func createHash(password string) string {
 	h := sha256.Sum256([]byte(password))
 	return hex.EncodeToString(h[:])
@@ -0,0 +1,107 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpContains Op = "contains"
+	OpGt       Op = "gt"
+	OpLt       Op = "lt"
+	OpIn       Op = "in"
+)
+
+type FieldType int
+
+const (
+	TypeText FieldType = iota
+	TypeInt
+	TypeTime
+)
+
+var Fields = map[string]FieldType{
+	"id":          TypeInt,
+	"name":        TypeText,
+	"description": TypeText,
+	"status":      TypeText,
+	"owner":       TypeInt,
+	"project_id":  TypeInt,
+	"parent_id":   TypeInt,
+	"priority":    TypeInt,
+	"due_at":      TypeTime,
+}
+
+type Expr interface {
+	Validate() error
+}
+
+type Condition struct {
+	Field  string
+	Op     Op
+	Values []string
+}
+
+type Group struct {
+	Or    bool
+	Exprs []Expr
+}
+
+func (c *Condition) Validate() error {
+	typ, ok := Fields[c.Field]
+	if !ok {
+		return fmt.Errorf("unknown field %q", c.Field)
+	}
+
+	switch c.Op {
+	case OpEq, OpNe, OpGt, OpLt:
+		if len(c.Values) != 1 {
+			return fmt.Errorf("operator %s on %s expects a single value", c.Op, c.Field)
+		}
+	case OpContains:
+		if typ != TypeText {
+			return fmt.Errorf("operator contains is not supported on %s", c.Field)
+		}
+		if len(c.Values) != 1 {
+			return fmt.Errorf("operator contains on %s expects a single value", c.Field)
+		}
+	case OpIn:
+		if len(c.Values) == 0 {
+			return fmt.Errorf("operator in on %s expects at least one value", c.Field)
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+
+	for _, v := range c.Values {
+		switch typ {
+		case TypeInt:
+			if _, err := strconv.Atoi(v); err != nil {
+				return fmt.Errorf("invalid integer %q for %s", v, c.Field)
+			}
+		case TypeTime:
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				return fmt.Errorf("invalid time %q for %s, expected RFC 3339", v, c.Field)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *Group) Validate() error {
+	if len(g.Exprs) == 0 {
+		return fmt.Errorf("empty group")
+	}
+	for _, e := range g.Exprs {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const maxDepth = 8
+
+// Parse reads an expression such as
+//
+//	or(status:in:todo|review,and(priority:gt:2,name:contains:"bug, urgent"))
+//
+// A comma-separated list at the top level is combined with AND.
+func Parse(s string) (Expr, error) {
+	p := &parser{input: s}
+
+	exprs, err := p.list(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+
+	var e Expr = &Group{Exprs: exprs}
+	if len(exprs) == 1 {
+		e = exprs[0]
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("filter at position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) list(depth int) ([]Expr, error) {
+	var exprs []Expr
+	for {
+		e, err := p.expr(depth)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+
+		if p.peek() != ',' {
+			return exprs, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) expr(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, p.errorf("nesting is too deep")
+	}
+
+	for _, op := range []string{"and(", "or("} {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			p.pos += len(op)
+
+			exprs, err := p.list(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ')' {
+				return nil, p.errorf("expected ')'")
+			}
+			p.pos++
+
+			return &Group{Or: op == "or(", Exprs: exprs}, nil
+		}
+	}
+
+	return p.condition()
+}
+
+func (p *parser) condition() (Expr, error) {
+	field, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ':' {
+		return nil, p.errorf("expected ':' after field %q", field)
+	}
+	p.pos++
+
+	op, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ':' {
+		return nil, p.errorf("expected ':' after operator %q", op)
+	}
+	p.pos++
+
+	var values []string
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+
+	return &Condition{Field: field, Op: Op(op), Values: values}, nil
+}
+
+func (p *parser) ident() (string, error) {
+	start := p.pos
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			break
+		}
+		p.pos++
+	}
+	if start == p.pos {
+		return "", p.errorf("expected identifier")
+	}
+	return p.input[start:p.pos], nil
+}
+
+func (p *parser) value() (string, error) {
+	if p.peek() != '"' {
+		start := p.pos
+		for p.pos < len(p.input) && !strings.ContainsRune(",|()\"", rune(p.input[p.pos])) {
+			p.pos++
+		}
+		return p.input[start:p.pos], nil
+	}
+
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.input):
+			b.WriteByte(p.input[p.pos+1])
+			p.pos += 2
+		case c == '"':
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	return "", p.errorf("unterminated quoted value")
+}
@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func nested(n int, inner string) string {
+	return strings.Repeat("and(", n) + inner + strings.Repeat(")", n)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Expr
+	}{
+		{"condition", "status:eq:todo",
+			&Condition{Field: "status", Op: OpEq, Values: []string{"todo"}}},
+		{"list of values", "status:in:todo|review",
+			&Condition{Field: "status", Op: OpIn, Values: []string{"todo", "review"}}},
+		{"top level and", "priority:gt:2,status:ne:done",
+			&Group{Exprs: []Expr{
+				&Condition{Field: "priority", Op: OpGt, Values: []string{"2"}},
+				&Condition{Field: "status", Op: OpNe, Values: []string{"done"}},
+			}}},
+		{"documented example", `or(status:in:todo|review,and(priority:gt:2,name:contains:"bug, urgent"))`,
+			&Group{Or: true, Exprs: []Expr{
+				&Condition{Field: "status", Op: OpIn, Values: []string{"todo", "review"}},
+				&Group{Exprs: []Expr{
+					&Condition{Field: "priority", Op: OpGt, Values: []string{"2"}},
+					&Condition{Field: "name", Op: OpContains, Values: []string{"bug, urgent"}},
+				}},
+			}}},
+		{"escaped quote", `name:contains:"say \"hi\" (now)"`,
+			&Condition{Field: "name", Op: OpContains, Values: []string{`say "hi" (now)`}}},
+		{"empty value", "description:eq:",
+			&Condition{Field: "description", Op: OpEq, Values: []string{""}}},
+		{"time", "due_at:lt:2026-01-01T00:00:00Z",
+			&Condition{Field: "due_at", Op: OpLt, Values: []string{"2026-01-01T00:00:00Z"}}},
+		{"deepest nesting", nested(maxDepth, "id:eq:1"),
+			func() Expr {
+				var e Expr = &Condition{Field: "id", Op: OpEq, Values: []string{"1"}}
+				for i := 0; i < maxDepth; i++ {
+					e = &Group{Exprs: []Expr{e}}
+				}
+				return e
+			}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"", "position 0: expected identifier"},
+		{"status", `position 6: expected ':' after field "status"`},
+		{"status:eq", `expected ':' after operator "eq"`},
+		{"and()", "position 4: expected identifier"},
+		{"or(status:eq:a", "expected ')'"},
+		{"status:eq:a)", `position 11: unexpected ')'`},
+		{`name:eq:"abc`, "unterminated quoted value"},
+		{nested(maxDepth+1, "id:eq:1"), "nesting is too deep"},
+		{"color:eq:red", `unknown field "color"`},
+		{"status:like:x", `unknown operator "like"`},
+		{"priority:contains:2", "operator contains is not supported on priority"},
+		{"priority:eq:high", `invalid integer "high" for priority`},
+		{"due_at:gt:tomorrow", `invalid time "tomorrow" for due_at`},
+		{"status:eq:a|b", "operator eq on status expects a single value"},
+		{"or(id:eq:1,owner:in:x)", `invalid integer "x" for owner`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.err)
+			}
+		})
+	}
+}
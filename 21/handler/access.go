@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GrantTaskAccessHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessOwner) {
+		return
+	}
+
+	var req struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.UserID == 0 || req.UserID == userID {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.DB.GrantTaskAccess(id, req.UserID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			http.Error(w, fmt.Sprintf("User %d not found", req.UserID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to grant access to task: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to grant access to task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) RevokeTaskAccessHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	granteeID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessOwner) {
+		return
+	}
+
+	err = h.DB.RevokeTaskAccess(id, granteeID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			http.Error(w, fmt.Sprintf("User %d has no access to task %d", granteeID, id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to revoke access to task: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to revoke access to task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
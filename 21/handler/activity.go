@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"restapi/audit"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// parseActivityPage reads limit and before, the ID of the last event of the
+// previous page.
+func parseActivityPage(q url.Values) (int, *int64, error) {
+	limit, err := queryInt(q, "limit")
+	if err != nil || (limit != nil && (*limit <= 0 || *limit > maxActivityLimit)) {
+		return 0, nil, fmt.Errorf("invalid limit, expected 1 to %d", maxActivityLimit)
+	}
+	if limit == nil {
+		n := defaultActivityLimit
+		limit = &n
+	}
+
+	var before *int64
+	if s := q.Get("before"); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			return 0, nil, fmt.Errorf("invalid before: %q", s)
+		}
+		before = &id
+	}
+
+	return *limit, before, nil
+}
+
+func writeActivity(w http.ResponseWriter, r *http.Request, events []audit.Event, limit int) {
+	if len(events) == limit {
+		w.Header().Set("X-Next-Before", strconv.FormatInt(events[len(events)-1].ID, 10))
+	}
+
+	render(w, r, http.StatusOK, events)
+}
+
+func (h *Handler) GetTaskActivityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, before, err := parseActivityPage(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	events, err := h.DB.GetTaskEvents(id, limit, before)
+	if err != nil {
+		log.Printf("Failed to get task activity from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get task activity from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeActivity(w, r, events, limit)
+}
+
+// GetUserActivityHandler returns the changes made by a user. Users can read
+// their own activity, admins can read anyone's.
+func (h *Handler) GetUserActivityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	limit, before, err := parseActivityPage(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if id != userID {
+		isAdmin, err := h.DB.IsAdmin(userID)
+		if err != nil {
+			log.Printf("Failed to check admin rights: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to check admin rights: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if !isAdmin {
+			http.Error(w, fmt.Sprintf("Access to activity of user %d denied", id), http.StatusForbidden)
+			return
+		}
+	}
+
+	events, err := h.DB.GetUserEvents(id, limit, before)
+	if err != nil {
+		log.Printf("Failed to get user activity from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get user activity from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeActivity(w, r, events, limit)
+}
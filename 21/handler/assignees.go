@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) AssignTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	var req struct {
+		UserID int `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.UserID == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err = h.DB.AssignTask(id, req.UserID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			http.Error(w, fmt.Sprintf("User %d not found", req.UserID), http.StatusUnprocessableEntity)
+		} else {
+			log.Printf("Failed to assign task: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to assign task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UnassignTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	assigneeID, err := strconv.Atoi(mux.Vars(r)["user_id"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	err = h.DB.UnassignTask(id, assigneeID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			http.Error(w, fmt.Sprintf("User %d is not assigned to task %d", assigneeID, id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to unassign task: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to unassign task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetAssigneesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	assignees, err := h.DB.GetAssignees(id)
+	if err != nil {
+		log.Printf("Failed to get assignees from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get assignees from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, assignees)
+}
+
+func (h *Handler) WatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	if err = h.DB.WatchTask(id, userID, auditMeta(r, userID)); err != nil {
+		log.Printf("Failed to watch task: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to watch task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) UnwatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	err = h.DB.UnwatchTask(id, userID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d is not watched", id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to unwatch task: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to unwatch task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetWatchersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	watchers, err := h.DB.GetWatchers(id)
+	if err != nil {
+		log.Printf("Failed to get watchers from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get watchers from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, watchers)
+}
+
+func (h *Handler) GetMyTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	page, err := h.DB.GetSelectedTasks(&task.Filter{
+		UserID:     userID,
+		AssigneeID: &userID,
+	})
+	if err != nil {
+		log.Printf("Failed to get assigned tasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get assigned tasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, page.Tasks)
+}
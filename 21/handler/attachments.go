@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"restapi/blob"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const defaultMaxAttachmentSize = 10 << 20
+
+var defaultAttachmentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"text/plain", "application/pdf", "application/json", "application/zip", "application/x-gzip",
+}
+
+func (h *Handler) UploadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxAttachmentSize+1<<20)
+	defer r.Body.Close()
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid multipart body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			http.Error(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid multipart body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if part.FormName() == "file" {
+			h.storeAttachment(w, r, id, userID, part)
+			return
+		}
+		part.Close()
+	}
+}
+
+func (h *Handler) storeAttachment(w http.ResponseWriter, r *http.Request, taskID, userID int, part io.Reader) {
+	filename := "attachment"
+	if p, ok := part.(interface{ FileName() string }); ok && p.FileName() != "" {
+		filename = filepath.Base(p.FileName())
+	}
+
+	br := bufio.NewReaderSize(part, 512)
+	head, err := br.Peek(512)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		http.Error(w, fmt.Sprintf("Failed to read file: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !h.AttachmentTypes[contentType] {
+		http.Error(w, "Unsupported file type: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	key, err := blob.NewKey()
+	if err != nil {
+		log.Printf("Failed to store attachment: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to store attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	size, err := h.Blobs.Put(key, &io.LimitedReader{R: br, N: h.MaxAttachmentSize + 1})
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("File exceeds %d bytes", h.MaxAttachmentSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		log.Printf("Failed to store attachment: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to store attachment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if size > h.MaxAttachmentSize {
+		if err = h.Blobs.Delete(key); err != nil {
+			log.Printf("Failed to delete attachment blob %s: %v", key, err)
+		}
+		http.Error(w, fmt.Sprintf("File exceeds %d bytes", h.MaxAttachmentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	attachment, err := h.DB.AddAttachment(&task.Attachment{
+		TaskID:      taskID,
+		Uploader:    userID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+		StorageKey:  key,
+	}, auditMeta(r, userID))
+	if err != nil {
+		if err := h.Blobs.Delete(key); err != nil {
+			log.Printf("Failed to delete attachment blob %s: %v", key, err)
+		}
+		if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found", taskID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to insert attachment into DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to insert attachment into DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render(w, r, http.StatusCreated, attachment)
+}
+
+func (h *Handler) GetAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	attachments, err := h.DB.GetAttachments(id)
+	if err != nil {
+		log.Printf("Failed to get attachments from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get attachments from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, attachments)
+}
+
+func (h *Handler) DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(mux.Vars(r)["attachment_id"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	attachment, err := h.DB.GetAttachment(id, attachmentID)
+	if err != nil {
+		if errors.Is(err, db.ErrAttachmentNotFound) {
+			http.Error(w, fmt.Sprintf("Attachment %d not found", attachmentID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to get attachment from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to get attachment from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	rc, err := h.Blobs.Get(attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, blob.ErrBlobNotFound) {
+			http.Error(w, fmt.Sprintf("Content of attachment %d not found", attachmentID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to read attachment: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to read attachment: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": attachment.Filename,
+	}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, rc); err != nil {
+		log.Printf("Failed to send attachment %d: %v", attachmentID, err)
+	}
+}
+
+func (h *Handler) DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(mux.Vars(r)["attachment_id"])
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	key, err := h.DB.DeleteAttachment(id, attachmentID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrAttachmentNotFound) {
+			http.Error(w, fmt.Sprintf("Attachment %d not found", attachmentID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to delete attachment from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to delete attachment from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Blobs.Delete(key); err != nil {
+		log.Printf("Failed to delete attachment blob %s: %v", key, err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
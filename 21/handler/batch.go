@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/project"
+	"restapi/task"
+)
+
+const maxBatchSize = 100
+
+type batchRequest struct {
+	// Mode is "atomic" (the default) or "best_effort".
+	Mode       string `json:"mode"`
+	Operations []struct {
+		Op       task.BatchOpKind `json:"op"`
+		ID       int              `json:"id"`
+		Task     *task.Task       `json:"task"`
+		Version  *int             `json:"version"`
+		Subtasks task.DeleteMode  `json:"subtasks"`
+	} `json:"operations"`
+}
+
+type batchResult struct {
+	Index  int              `json:"index"`
+	Op     task.BatchOpKind `json:"op"`
+	Status int              `json:"status"`
+	Task   *task.Task       `json:"task,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Committed bool          `json:"committed"`
+	Results   []batchResult `json:"results"`
+}
+
+// prepareBatchOp validates one operation and checks that the user may run it.
+// Project checks are memoized in projects since batches tend to share them.
+func (h *Handler) prepareBatchOp(userID int, op *task.BatchOp, projects map[int]*statusError) *statusError {
+	switch op.Op {
+	case task.BatchCreate:
+		t := op.Task
+		if t == nil || !validTask(t) {
+			return &statusError{http.StatusBadRequest, "Invalid task"}
+		}
+		t.Owner = userID
+		t.Status = h.Workflow.Initial
+		if t.Language == "" {
+			t.Language = task.DefaultLanguage
+		}
+		if t.ProjectID != nil {
+			err, ok := projects[*t.ProjectID]
+			if !ok {
+				err = h.checkProject(*t.ProjectID, userID, project.RoleEditor)
+				projects[*t.ProjectID] = err
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if t.ParentID != nil {
+			return h.checkTask(*t.ParentID, userID, task.AccessWrite)
+		}
+		return nil
+	case task.BatchUpdate:
+		if op.ID == 0 || op.Task == nil || !validTask(op.Task) {
+			return &statusError{http.StatusBadRequest, "Invalid task"}
+		}
+		return h.checkTask(op.ID, userID, task.AccessWrite)
+	case task.BatchDelete:
+		if op.ID == 0 {
+			return &statusError{http.StatusBadRequest, "Invalid task ID"}
+		}
+		if op.Mode == "" {
+			op.Mode = h.DeleteMode
+		} else if _, err := task.ParseDeleteMode(string(op.Mode)); err != nil {
+			return &statusError{http.StatusBadRequest, err.Error()}
+		}
+		return h.checkTask(op.ID, userID, task.AccessOwner)
+	}
+	return &statusError{http.StatusBadRequest, fmt.Sprintf("Unknown operation %q", op.Op)}
+}
+
+// missingReferenceMessage explains a create that failed because its parent
+// task or project was removed after it was checked.
+func missingReferenceMessage(t *task.Task) string {
+	switch {
+	case t == nil || t.ParentID == nil && t.ProjectID == nil:
+		return "Referenced task or project not found"
+	case t.ProjectID == nil:
+		return fmt.Sprintf("Parent task %d not found", *t.ParentID)
+	case t.ParentID == nil:
+		return fmt.Sprintf("Project %d not found", *t.ProjectID)
+	}
+	return fmt.Sprintf("Parent task %d or project %d not found", *t.ParentID, *t.ProjectID)
+}
+
+func batchOutcomeStatus(op *task.BatchOp, err error) (int, string) {
+	switch {
+	case err == nil && op.Op == task.BatchCreate:
+		return http.StatusCreated, ""
+	case err == nil && op.Op == task.BatchDelete:
+		return http.StatusNoContent, ""
+	case err == nil:
+		return http.StatusOK, ""
+	case errors.Is(err, db.ErrTaskNotFound) && op.Op == task.BatchCreate:
+		return http.StatusNotFound, missingReferenceMessage(op.Task)
+	case errors.Is(err, db.ErrTaskNotFound):
+		return http.StatusNotFound, fmt.Sprintf("Task %d not found", op.ID)
+	case errors.Is(err, db.ErrVersionConflict):
+		return http.StatusPreconditionFailed, fmt.Sprintf("Task %d was modified, reload it and retry", op.ID)
+	case errors.Is(err, db.ErrTaskHasSubtasks):
+		return http.StatusConflict, fmt.Sprintf("Task %d has subtasks", op.ID)
+	}
+	log.Printf("Failed to run batch operation: %v", err)
+	return http.StatusInternalServerError, err.Error()
+}
+
+// BatchTasksHandler runs a list of create, update and delete operations in
+// one transaction. In atomic mode any failure rolls back the whole batch and
+// the response is 422; in best_effort mode failed operations are skipped.
+func (h *Handler) BatchTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	atomic := req.Mode == "" || req.Mode == "atomic"
+	if !atomic && req.Mode != "best_effort" {
+		http.Error(w, "Invalid mode, expected atomic or best_effort", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchSize {
+		http.Error(w, fmt.Sprintf("Expected 1 to %d operations", maxBatchSize), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchResult, len(req.Operations))
+	ops := make([]task.BatchOp, 0, len(req.Operations))
+	indexes := make([]int, 0, len(req.Operations))
+	projects := make(map[int]*statusError)
+	failed := false
+	for i, o := range req.Operations {
+		op := task.BatchOp{Op: o.Op, ID: o.ID, Task: o.Task, Mode: o.Subtasks}
+		if o.Version != nil {
+			op.IfMatch = []int{*o.Version}
+		}
+
+		results[i] = batchResult{Index: i, Op: o.Op, Status: http.StatusFailedDependency}
+		if err := h.prepareBatchOp(userID, &op, projects); err != nil {
+			results[i].Status, results[i].Error = err.status, err.msg
+			failed = true
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	resp := batchResponse{Results: results}
+	if atomic && failed {
+		writeBatchResponse(w, r, &resp)
+		return
+	}
+
+	outcomes, err := h.DB.RunBatch(ops, atomic, auditMeta(r, userID))
+	if err != nil && !errors.Is(err, db.ErrBatchAborted) {
+		log.Printf("Failed to run batch in DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to run batch in DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+	resp.Committed = err == nil
+
+	for j, o := range outcomes {
+		res := &results[indexes[j]]
+		res.Status, res.Error = batchOutcomeStatus(&ops[j], o.Err)
+		if !resp.Committed {
+			if o.Err == nil {
+				res.Status = http.StatusFailedDependency
+			}
+			continue
+		}
+
+		res.Task = o.Task
+		for _, id := range o.Touched {
+			if err := h.Cache.Delete(id); err != nil {
+				log.Printf("Failed to delete from cache: %v", err)
+			}
+		}
+	}
+
+	writeBatchResponse(w, r, &resp)
+}
+
+func writeBatchResponse(w http.ResponseWriter, r *http.Request, resp *batchResponse) {
+	status := http.StatusOK
+	if !resp.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+
+	render(w, r, status, resp)
+}
@@ -0,0 +1,9 @@
+package handler
+
+import "io"
+
+type BlobStore interface {
+	Put(key string, r io.Reader) (int64, error)
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
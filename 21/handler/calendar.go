@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"restapi/db"
+	"restapi/ical"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+const calendarMaxTasks = 5000
+
+// CreateCalendarTokenHandler issues a new calendar feed token for the user,
+// revoking the previous one. Calendar apps cannot send an Authorization
+// header, so the token is part of the feed URL and is shown only once.
+func (h *Handler) CreateCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to generate token: %v", err), http.StatusInternalServerError)
+		return
+	}
+	token := hex.EncodeToString(b)
+
+	if err := h.DB.SetCalendarToken(userID, token); err != nil {
+		log.Printf("Failed to set calendar token in DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to set calendar token in DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusCreated, map[string]string{
+		"token": token,
+		"url":   h.baseURL(r) + "/calendar/" + token + ".ics",
+	})
+}
+
+func (h *Handler) DeleteCalendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.DB.DeleteCalendarToken(userID); err != nil {
+		if errors.Is(err, db.ErrCalendarTokenNotFound) {
+			http.Error(w, "Calendar token not found", http.StatusNotFound)
+		} else {
+			log.Printf("Failed to delete calendar token from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to delete calendar token from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CalendarFeedHandler serves the tasks with a due date that the token's owner
+// can see as an iCalendar feed, as VTODO entries or, with component=event,
+// as VEVENT entries for calendars that do not show to-dos. The feed can be
+// narrowed with project_id and label. Its ETag is derived from the IDs and
+// versions of the matching tasks, which are checked before the feed is built,
+// so polling clients sending If-None-Match get a cheap 304 while nothing
+// changed. Only the first calendarMaxTasks tasks by due date are listed; a
+// longer feed carries X-Calendar-Truncated and says so in its description.
+func (h *Handler) CalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	component := q.Get("component")
+	if component != "" && component != "todo" && component != "event" {
+		http.Error(w, "Invalid component, expected todo or event", http.StatusBadRequest)
+		return
+	}
+
+	projectID, err := queryInt(q, "project_id")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.DB.GetCalendarUser(mux.Vars(r)["token"])
+	if err != nil {
+		if errors.Is(err, db.ErrCalendarTokenNotFound) {
+			http.Error(w, "Calendar not found", http.StatusNotFound)
+		} else {
+			log.Printf("Failed to get calendar token from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to get calendar token from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	limit, noComments := calendarMaxTasks, 0
+	f, ferr := h.selectedTasksFilter(userID, &GetSelectedTasksRequest{
+		ProjectID: projectID,
+		LabelsAny: queryList(q, "label"),
+		Sort:      []task.SortKey{{Field: "due_at"}},
+		Limit:     &limit,
+		Comments:  &noComments,
+	})
+	if ferr != nil {
+		ferr.write(w)
+		return
+	}
+	f.HasDue = true
+
+	stamp, err := h.DB.GetTasksStamp(f)
+	if err != nil {
+		log.Printf("Failed to get tasks stamp from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get tasks stamp from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	base := h.baseURL(r)
+	truncated := stamp.Count > calendarMaxTasks
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\n%s\n%d\n%s", component, base, stamp.Count, stamp.Digest)))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Header().Set("X-Total-Count", strconv.Itoa(stamp.Count))
+	if truncated {
+		w.Header().Set("X-Calendar-Truncated", "true")
+	}
+	if etagMatches(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	page, err := h.DB.GetSelectedTasks(f)
+	if err != nil {
+		log.Printf("Failed to get selected tasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get selected tasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	host := base
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	components := make([]ical.Component, len(page.Tasks))
+	for i := range page.Tasks {
+		if component == "event" {
+			components[i] = h.taskEvent(&page.Tasks[i], base, host)
+		} else {
+			components[i] = h.taskTodo(&page.Tasks[i], base, host)
+		}
+	}
+
+	props := []ical.Prop{
+		{Name: "VERSION", Value: "2.0"},
+		{Name: "PRODID", Value: "-//restapi//Tasks//EN"},
+		{Name: "CALSCALE", Value: "GREGORIAN"},
+		{Name: "METHOD", Value: "PUBLISH"},
+		{Name: "NAME", Value: "Tasks"},
+		{Name: "X-WR-CALNAME", Value: "Tasks"},
+		{Name: "REFRESH-INTERVAL;VALUE=DURATION", Value: "PT1H"},
+		{Name: "X-PUBLISHED-TTL", Value: "PT1H"},
+	}
+	if truncated {
+		desc := fmt.Sprintf("Only the first %d of %d tasks by due date are shown", calendarMaxTasks, stamp.Count)
+		props = append(props,
+			ical.Prop{Name: "DESCRIPTION", Value: ical.Text(desc)},
+			ical.Prop{Name: "X-WR-CALDESC", Value: ical.Text(desc)},
+		)
+	}
+
+	var body bytes.Buffer
+	if err = ical.Encode(&body, props, components); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode calendar: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", "inline;filename=tasks.ics")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// taskProps returns the properties shared by to-dos and events. DTSTAMP is
+// the creation time rather than now so that an unchanged feed is unchanged
+// byte for byte, and SEQUENCE follows the task version so clients notice edits.
+func taskProps(t *task.Task, base, host string) []ical.Prop {
+	props := []ical.Prop{
+		{Name: "UID", Value: "task-" + strconv.Itoa(t.ID) + "@" + host},
+		{Name: "DTSTAMP", Value: ical.DateTime(t.CreatedAt)},
+		{Name: "CREATED", Value: ical.DateTime(t.CreatedAt)},
+		{Name: "SEQUENCE", Value: strconv.Itoa(t.Version - 1)},
+		{Name: "SUMMARY", Value: ical.Text(t.Name)},
+		{Name: "URL", Value: base + "/tasks/" + strconv.Itoa(t.ID)},
+	}
+	if len(t.Labels) > 0 {
+		props = append(props, ical.Prop{Name: "CATEGORIES", Value: ical.TextList(t.Labels)})
+	}
+	return props
+}
+
+func (h *Handler) taskTodo(t *task.Task, base, host string) ical.Component {
+	status := "IN-PROCESS"
+	switch {
+	case h.Workflow.IsClosed(t.Status):
+		status = "COMPLETED"
+	case t.Status == h.Workflow.Initial:
+		status = "NEEDS-ACTION"
+	}
+
+	props := append(taskProps(t, base, host),
+		ical.Prop{Name: "DUE", Value: ical.DateTime(*t.DueAt)},
+		ical.Prop{Name: "STATUS", Value: status},
+		ical.Prop{Name: "DESCRIPTION", Value: ical.Text(t.Description)},
+	)
+	return ical.Component{Name: "VTODO", Props: props}
+}
+
+// taskEvent places the task at its due time. Events have no status fitting a
+// task, so the workflow status heads the description instead.
+func (h *Handler) taskEvent(t *task.Task, base, host string) ical.Component {
+	props := append(taskProps(t, base, host),
+		ical.Prop{Name: "DTSTART", Value: ical.DateTime(*t.DueAt)},
+		ical.Prop{Name: "TRANSP", Value: "TRANSPARENT"},
+		ical.Prop{Name: "DESCRIPTION", Value: ical.Text("Status: " + string(t.Status) + "\n\n" + t.Description)},
+	)
+	return ical.Component{Name: "VEVENT", Props: props}
+}
+
+// baseURL is the public address of the API, taken from PUBLIC_URL or else
+// from the request.
+func (h *Handler) baseURL(r *http.Request) string {
+	if h.PublicURL != "" {
+		return h.PublicURL
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
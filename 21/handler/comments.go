@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultCommentsLimit = 20
+	maxCommentsLimit     = 100
+)
+
+func (h *Handler) authorizeComment(w http.ResponseWriter, taskID, commentID, userID int) bool {
+	if !h.authorizeTask(w, taskID, userID, task.AccessRead) {
+		return false
+	}
+
+	comment, err := h.DB.GetComment(taskID, commentID)
+	if err != nil {
+		if errors.Is(err, db.ErrCommentNotFound) {
+			http.Error(w, fmt.Sprintf("Comment %d not found", commentID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to get comment from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to get comment from DB: %v", err), http.StatusInternalServerError)
+		}
+		return false
+	}
+
+	if comment.Author == userID {
+		return true
+	}
+
+	isAdmin, err := h.DB.IsAdmin(userID)
+	if err != nil {
+		log.Printf("Failed to check user role: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to check user role: %v", err), http.StatusInternalServerError)
+		return false
+	}
+	if !isAdmin {
+		http.Error(w, fmt.Sprintf("Access to comment %d denied", commentID), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+func (h *Handler) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["comment_id"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Text == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeComment(w, id, commentID, userID) {
+		return
+	}
+
+	comment, err := h.DB.UpdateComment(commentID, req.Text, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrCommentNotFound) {
+			http.Error(w, fmt.Sprintf("Comment %d not found", commentID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to update comment in DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to update comment in DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	render(w, r, http.StatusOK, comment)
+}
+
+func (h *Handler) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	commentID, err := strconv.Atoi(mux.Vars(r)["comment_id"])
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeComment(w, id, commentID, userID) {
+		return
+	}
+
+	err = h.DB.DeleteComment(commentID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrCommentNotFound) {
+			http.Error(w, fmt.Sprintf("Comment %d not found", commentID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to delete comment from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to delete comment from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+
+	limit, err := queryInt(q, "limit")
+	if err != nil || (limit != nil && (*limit <= 0 || *limit > maxCommentsLimit)) {
+		http.Error(w, fmt.Sprintf("Invalid limit, expected 1 to %d", maxCommentsLimit), http.StatusBadRequest)
+		return
+	}
+	if limit == nil {
+		n := defaultCommentsLimit
+		limit = &n
+	}
+
+	offset, err := queryInt(q, "offset")
+	if err != nil || (offset != nil && *offset < 0) {
+		http.Error(w, "Invalid offset", http.StatusBadRequest)
+		return
+	}
+	if offset == nil {
+		n := 0
+		offset = &n
+	}
+
+	sort := strings.ToLower(q.Get("sort"))
+	if sort != "" && sort != "asc" && sort != "desc" {
+		http.Error(w, "Invalid sort, expected asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	comments, total, err := h.DB.GetComments(id, *limit, *offset, sort == "desc")
+	if err != nil {
+		log.Printf("Failed to get comments from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get comments from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	render(w, r, http.StatusOK, comments)
+}
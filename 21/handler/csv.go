@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"restapi/task"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var taskCSVHeader = []string{"ID", "Name", "Description", "Owner", "Project", "Status", "Due", "Priority", "Labels"}
+
+func taskCSVRecord(t *task.Task) []string {
+	projectID := ""
+	if t.ProjectID != nil {
+		projectID = strconv.Itoa(*t.ProjectID)
+	}
+
+	dueAt := ""
+	if t.DueAt != nil {
+		dueAt = t.DueAt.Format(time.RFC3339)
+	}
+
+	priority := ""
+	if t.Priority != nil {
+		priority = strconv.Itoa(*t.Priority)
+	}
+
+	return []string{
+		strconv.Itoa(t.ID),
+		t.Name,
+		t.Description,
+		strconv.Itoa(t.Owner),
+		projectID,
+		string(t.Status),
+		dueAt,
+		priority,
+		strings.Join(t.Labels, ","),
+	}
+}
+
+var commentCSVHeader = []string{"ID", "Task", "Parent", "Author", "Text", "Created", "Edited", "Deleted"}
+
+func commentCSVRecord(c *task.Comment) []string {
+	parentID := ""
+	if c.ParentID != nil {
+		parentID = strconv.Itoa(*c.ParentID)
+	}
+
+	editedAt := ""
+	if c.EditedAt != nil {
+		editedAt = c.EditedAt.Format(time.RFC3339)
+	}
+
+	return []string{
+		strconv.Itoa(c.ID),
+		strconv.Itoa(c.TaskID),
+		parentID,
+		strconv.Itoa(c.Author),
+		c.Text,
+		c.CreatedAt.Format(time.RFC3339),
+		editedAt,
+		strconv.FormatBool(c.Deleted),
+	}
+}
+
+// taskCSVColumns maps the header of an imported file to column indexes.
+// Columns may come in any order and all but Name and Description are optional.
+func taskCSVColumns(header []string) (map[string]int, error) {
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		known := ""
+		for _, h := range taskCSVHeader {
+			if strings.EqualFold(name, h) {
+				known = h
+			}
+		}
+		if known == "" {
+			return nil, fmt.Errorf("unknown column %q, allowed: %s", name, strings.Join(taskCSVHeader, ", "))
+		}
+		if _, ok := columns[known]; ok {
+			return nil, fmt.Errorf("duplicate column %q", known)
+		}
+		columns[known] = i
+	}
+
+	for _, name := range []string{"Name", "Description"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	return columns, nil
+}
+
+// parseTaskCSVRecord is the reverse of taskCSVRecord. It returns the task ID
+// (0 if empty) and the task; Owner and Status are not read.
+func parseTaskCSVRecord(record []string, columns map[string]int) (int, *task.Task, error) {
+	field := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	for _, v := range record {
+		if !utf8.ValidString(v) {
+			return 0, nil, fmt.Errorf("invalid UTF-8 text, check the encoding")
+		}
+	}
+
+	id := 0
+	if s := field("ID"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return 0, nil, fmt.Errorf("invalid ID: %q", s)
+		}
+		id = v
+	}
+
+	t := &task.Task{
+		Name:        field("Name"),
+		Description: field("Description"),
+	}
+
+	if s := field("Project"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid Project: %q", s)
+		}
+		t.ProjectID = &v
+	}
+
+	if s := field("Due"); s != "" {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			if v, err = time.Parse(time.DateOnly, s); err != nil {
+				return 0, nil, fmt.Errorf("invalid Due: %q, expected RFC 3339 or YYYY-MM-DD", s)
+			}
+		}
+		t.DueAt = &v
+	}
+
+	if s := field("Priority"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, nil, fmt.Errorf("invalid Priority: %q", s)
+		}
+		t.Priority = &v
+	}
+
+	for _, l := range strings.Split(field("Labels"), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			t.Labels = append(t.Labels, l)
+		}
+	}
+
+	return id, t, nil
+}
+
+// parseCSVDelimiter accepts a single character or "tab".
+func parseCSVDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return ',', nil
+	case "tab", `\t`:
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size != len(s) || r == utf8.RuneError || r == '"' || r == '\r' || r == '\n' {
+		return 0, fmt.Errorf("invalid delimiter: %q", s)
+	}
+	return r, nil
+}
+
+// windows1252 holds the characters of 0x80-0x9f, the only range where
+// Windows-1252 differs from Latin-1. Unassigned bytes are zero.
+var windows1252 = [32]rune{
+	0x20ac, 0, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+	0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017d, 0,
+	0, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+	0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0, 0x017e, 0x0178,
+}
+
+// csvDecoder returns a reader that converts r from the named encoding to
+// UTF-8. A leading byte order mark is dropped.
+func csvDecoder(r io.Reader, encoding string) (io.Reader, error) {
+	br := bufio.NewReader(r)
+
+	switch strings.ToLower(encoding) {
+	case "", "utf-8", "utf8":
+		if bom, _ := br.Peek(3); string(bom) == "\xef\xbb\xbf" {
+			br.Discard(3)
+		}
+		return br, nil
+	case "latin1", "latin-1", "iso-8859-1":
+		return &decodeReader{src: br, next: func(br *bufio.Reader) (rune, error) {
+			b, err := br.ReadByte()
+			return rune(b), err
+		}}, nil
+	case "windows-1252", "cp1252":
+		return &decodeReader{src: br, next: func(br *bufio.Reader) (rune, error) {
+			b, err := br.ReadByte()
+			if err == nil && b >= 0x80 && b < 0xa0 && windows1252[b-0x80] != 0 {
+				return windows1252[b-0x80], nil
+			}
+			return rune(b), err
+		}}, nil
+	case "utf-16", "utf-16le", "utf-16be":
+		bigEndian := strings.ToLower(encoding) != "utf-16le"
+		if bom, _ := br.Peek(2); string(bom) == "\xfe\xff" || string(bom) == "\xff\xfe" {
+			bigEndian = bom[0] == 0xfe
+			br.Discard(2)
+		}
+		return &decodeReader{src: br, next: func(br *bufio.Reader) (rune, error) {
+			return readUTF16(br, bigEndian)
+		}}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported encoding: %q, allowed: utf-8, utf-16, utf-16le, utf-16be, latin1, windows-1252", encoding)
+}
+
+func readUTF16(br *bufio.Reader, bigEndian bool) (rune, error) {
+	unit := func(b []byte) rune {
+		if bigEndian {
+			return rune(b[0])<<8 | rune(b[1])
+		}
+		return rune(b[1])<<8 | rune(b[0])
+	}
+
+	var b [2]byte
+	if _, err := io.ReadFull(br, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return utf8.RuneError, nil
+		}
+		return 0, err
+	}
+	r1 := unit(b[:])
+	if !utf16.IsSurrogate(r1) {
+		return r1, nil
+	}
+
+	// The second half is only consumed when it completes the pair, so the
+	// character after an unpaired surrogate is kept.
+	next, _ := br.Peek(2)
+	if len(next) < 2 {
+		return utf8.RuneError, nil
+	}
+	r := utf16.DecodeRune(r1, unit(next))
+	if r != utf8.RuneError {
+		br.Discard(2)
+	}
+	return r, nil
+}
+
+// decodeReader encodes the runes produced by next as UTF-8.
+type decodeReader struct {
+	src     *bufio.Reader
+	next    func(*bufio.Reader) (rune, error)
+	pending []byte
+}
+
+func (d *decodeReader) Read(p []byte) (int, error) {
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+
+	var buf [utf8.UTFMax]byte
+	for n < len(p) {
+		r, err := d.next(d.src)
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				return n, nil
+			}
+			return n, err
+		}
+
+		size := utf8.EncodeRune(buf[:], r)
+		copied := copy(p[n:], buf[:size])
+		n += copied
+		if copied < size {
+			d.pending = append(d.pending, buf[copied:size]...)
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"io"
+	"reflect"
+	"restapi/task"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestCSVDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		in       string
+		want     string
+	}{
+		{"utf-8", "", "a,b", "a,b"},
+		{"utf-8 bom", "UTF-8", "\xef\xbb\xbfa,b", "a,b"},
+		{"latin1", "latin1", "caf\xe9", "café"},
+		{"latin1 control range", "iso-8859-1", "\x80", "\u0080"},
+		{"windows-1252", "cp1252", "\x80 \x93q\x94 \xe9", "€ “q” é"},
+		{"windows-1252 unassigned", "windows-1252", "\x81", "\u0081"},
+		{"long latin1", "latin1", strings.Repeat("\xe9", 1000), strings.Repeat("é", 1000)},
+		{"utf-16 defaults to big endian", "utf-16", "\x00a\x00b", "ab"},
+		{"utf-16le", "utf-16le", "a\x00b\x00", "ab"},
+		{"utf-16 little endian bom", "utf-16", "\xff\xfea\x00", "a"},
+		{"bom overrides name", "utf-16le", "\xfe\xff\x00a", "a"},
+		{"surrogate pair", "utf-16be", "\xd8\x3d\xde\x00", "😀"},
+		{"unpaired high surrogate", "utf-16be", "\xd8\x3d\x00A", "�A"},
+		{"lone low surrogate", "utf-16be", "\xdc\x00\x00A", "�A"},
+		{"surrogate at end", "utf-16be", "\x00A\xd8\x3d", "A�"},
+		{"odd byte", "utf-16be", "\x00a\x00", "a�"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// One byte at a time exercises runes split across reads.
+			for _, wrap := range []func(io.Reader) io.Reader{nil, iotest.OneByteReader} {
+				r, err := csvDecoder(strings.NewReader(tt.in), tt.encoding)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if wrap != nil {
+					r = wrap(r)
+				}
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := csvDecoder(strings.NewReader(""), "ebcdic"); err == nil {
+		t.Error("csvDecoder accepted an unsupported encoding")
+	}
+}
+
+func TestParseCSVDelimiter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want rune
+		ok   bool
+	}{
+		{"", ',', true},
+		{"tab", '\t', true},
+		{`\t`, '\t', true},
+		{";", ';', true},
+		{"§", '§', true},
+		{"ab", 0, false},
+		{`"`, 0, false},
+		{"\n", 0, false},
+		{"\xff", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseCSVDelimiter(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseCSVDelimiter(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestTaskCSVColumns(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		want   map[string]int
+		err    string
+	}{
+		{"any order and case", []string{" description", "NAME", "Labels"},
+			map[string]int{"Description": 0, "Name": 1, "Labels": 2}, ""},
+		{"export header", taskCSVHeader,
+			map[string]int{"ID": 0, "Name": 1, "Description": 2, "Owner": 3, "Project": 4,
+				"Status": 5, "Due": 6, "Priority": 7, "Labels": 8}, ""},
+		{"unknown", []string{"Name", "Description", "Color"}, nil, `unknown column "Color"`},
+		{"duplicate", []string{"Name", "Description", "name"}, nil, `duplicate column "Name"`},
+		{"missing", []string{"Name"}, nil, `missing column "Description"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := taskCSVColumns(tt.header)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTaskCSVRecord(t *testing.T) {
+	columns, err := taskCSVColumns(taskCSVHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	project, priority := 4, 2
+
+	tests := []struct {
+		name   string
+		record []string
+		id     int
+		want   *task.Task
+		err    string
+	}{
+		{"full", []string{"12", " Name ", "Text", "99", "4", "done", "2026-03-01T09:30:00Z", "2", " a, ,b "}, 12,
+			&task.Task{Name: "Name", Description: "Text", ProjectID: &project, DueAt: &due, Priority: &priority,
+				Labels: []string{"a", "b"}}, ""},
+		{"date only", []string{"", "N", "D", "", "", "", "2026-03-01", "", ""}, 0,
+			&task.Task{Name: "N", Description: "D", DueAt: &day}, ""},
+		{"short record", []string{"", "N"}, 0, &task.Task{Name: "N"}, ""},
+		{"zero id", []string{"0", "N", "D"}, 0, nil, `invalid ID: "0"`},
+		{"bad id", []string{"x", "N", "D"}, 0, nil, `invalid ID: "x"`},
+		{"bad project", []string{"", "N", "D", "", "p"}, 0, nil, `invalid Project: "p"`},
+		{"bad due", []string{"", "N", "D", "", "", "", "tomorrow"}, 0, nil, `invalid Due: "tomorrow"`},
+		{"bad priority", []string{"", "N", "D", "", "", "", "", "high"}, 0, nil, `invalid Priority: "high"`},
+		{"bad encoding", []string{"", "caf\xe9", "D"}, 0, nil, "invalid UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, got, err := parseTaskCSVRecord(tt.record, columns)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got error %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != tt.id || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %d, %+v, want %d, %+v", id, got, tt.id, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskCSVRoundTrip(t *testing.T) {
+	columns, err := taskCSVColumns(taskCSVHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	due := time.Date(2026, 5, 17, 18, 0, 0, 0, time.UTC)
+	project, priority := 3, 0
+	in := &task.Task{ID: 8, Name: "Ship", Description: "Line one\nline two", Owner: 1, Status: "todo",
+		ProjectID: &project, DueAt: &due, Priority: &priority, Labels: []string{"ops", "q2"}}
+
+	id, got, err := parseTaskCSVRecord(taskCSVRecord(in), columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &task.Task{Name: in.Name, Description: in.Description, ProjectID: in.ProjectID, DueAt: in.DueAt,
+		Priority: in.Priority, Labels: in.Labels}
+	if id != in.ID || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d, %+v, want %d, %+v", id, got, in.ID, want)
+	}
+}
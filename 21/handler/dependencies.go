@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/filter"
+	"restapi/project"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) AddBlockerHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		BlockerID int `json:"blocker_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.BlockerID == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.BlockerID == id {
+		http.Error(w, "Task cannot block itself", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	if !h.authorizeTask(w, req.BlockerID, userID, task.AccessRead) {
+		return
+	}
+
+	dep, err := h.DB.AddDependency(id, req.BlockerID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, task.ErrDependencyCycle) {
+			http.Error(w, fmt.Sprintf("Task %d already depends on task %d", req.BlockerID, id), http.StatusConflict)
+		} else if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found", req.BlockerID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to add dependency: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to add dependency: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render(w, r, http.StatusCreated, dep)
+}
+
+func (h *Handler) RemoveBlockerHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	blockerID, err := strconv.Atoi(mux.Vars(r)["blocker_id"])
+	if err != nil {
+		http.Error(w, "Invalid blocker ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	err = h.DB.RemoveDependency(id, blockerID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrDependencyNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d is not blocked by task %d", id, blockerID), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to remove dependency: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to remove dependency: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetBlockersHandler(w http.ResponseWriter, r *http.Request) {
+	h.getDependentTasks(w, r, h.DB.GetBlockers)
+}
+
+func (h *Handler) GetBlockedHandler(w http.ResponseWriter, r *http.Request) {
+	h.getDependentTasks(w, r, h.DB.GetBlocked)
+}
+
+func (h *Handler) getDependentTasks(w http.ResponseWriter, r *http.Request, get func(taskID, userID int) ([]task.Task, error)) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	tasks, err := get(id, userID)
+	if err != nil {
+		log.Printf("Failed to get dependencies from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get dependencies from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, tasks)
+}
+
+// GetReadyTasksHandler lists open tasks whose blockers are all closed.
+func (h *Handler) GetReadyTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projectID, err := queryInt(r.URL.Query(), "project_id")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if projectID != nil && !h.authorizeProject(w, *projectID, userID, project.RoleViewer) {
+		return
+	}
+
+	page, err := h.DB.GetSelectedTasks(&task.Filter{
+		UserID:    userID,
+		ProjectID: projectID,
+		Ready:     true,
+		Closed:    h.Workflow.Closed,
+	})
+	if err != nil {
+		log.Printf("Failed to get ready tasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get ready tasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, page.Tasks)
+}
+
+// GetTaskOrderHandler returns the tasks given by ids or project_id ordered so
+// that every blocker comes before the tasks it blocks.
+func (h *Handler) GetTaskOrderHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	projectID, err := queryInt(q, "project_id")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	f := &task.Filter{UserID: userID, ProjectID: projectID}
+	if ids := queryList(q, "ids"); len(ids) > 0 {
+		cond := &filter.Condition{Field: "id", Op: filter.OpIn, Values: ids}
+		if err := cond.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+			return
+		}
+		f.Expr = cond
+	} else if projectID == nil {
+		http.Error(w, "Invalid query: ids or project_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if projectID != nil && !h.authorizeProject(w, *projectID, userID, project.RoleViewer) {
+		return
+	}
+
+	page, err := h.DB.GetSelectedTasks(f)
+	if err != nil {
+		log.Printf("Failed to get tasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get tasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	ids := make([]int, len(page.Tasks))
+	for i, t := range page.Tasks {
+		ids[i] = t.ID
+	}
+
+	deps, err := h.DB.GetDependencies(ids)
+	if err != nil {
+		log.Printf("Failed to get dependencies from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get dependencies from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	ordered, err := task.TopologicalOrder(page.Tasks, deps)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	render(w, r, http.StatusOK, ordered)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/task"
+)
+
+const defaultExportMaxRows = 100000
+
+// ExportTasksHandler streams the tasks matching the query parameters of
+// GetSelectedTasksHandler as NDJSON (the default) or CSV, picked by the
+// Accept header. Rows are written and flushed batch by batch while they are
+// read from the DB. At most ExportMaxRows rows are written, or limit if it is
+// lower; when more tasks matched the X-Export-Truncated trailer is set.
+// Errors after the first row can only be reported in the X-Export-Error
+// trailer.
+func (h *Handler) ExportTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	selectedTasksReq, err := parseSelectedTasksRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	mt, nerr := negotiate(r, mediaNDJSON, mediaCSV)
+	if nerr != nil {
+		nerr.write(w)
+		return
+	}
+	csvFormat := mt == mediaCSV
+	if selectedTasksReq.Cursor != "" || selectedTasksReq.WithTotal {
+		http.Error(w, "Invalid query: cursor and with_total are not supported by exports", http.StatusBadRequest)
+		return
+	}
+
+	f, ferr := h.selectedTasksFilter(userID, selectedTasksReq)
+	if ferr != nil {
+		ferr.write(w)
+		return
+	}
+
+	if f.Limit == nil || *f.Limit > h.ExportMaxRows {
+		maxRows := h.ExportMaxRows
+		f.Limit = &maxRows
+	}
+	if csvFormat {
+		noComments := 0
+		f.LatestComments = &noComments
+	}
+
+	flusher, _ := w.(http.Flusher)
+	csvWriter := csv.NewWriter(w)
+	encoder := json.NewEncoder(w)
+
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Trailer", "X-Export-Truncated, X-Export-Error")
+		if csvFormat {
+			w.Header().Set("Content-Type", mediaCSV)
+			w.Header().Set("Content-Disposition", "attachment;filename=tasks.csv")
+			w.WriteHeader(http.StatusOK)
+			return csvWriter.Write(taskCSVHeader)
+		}
+		w.Header().Set("Content-Type", mediaNDJSON)
+		w.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	truncated, err := h.DB.StreamSelectedTasks(r.Context(), f, func(tasks []task.Task) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+
+		for i := range tasks {
+			if csvFormat {
+				if err := csvWriter.Write(taskCSVRecord(&tasks[i])); err != nil {
+					return err
+				}
+			} else if err := encoder.Encode(&tasks[i]); err != nil {
+				return err
+			}
+		}
+
+		if csvFormat {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+		csvWriter.Flush()
+	}
+
+	if err != nil {
+		if r.Context().Err() != nil {
+			return
+		}
+		if !started {
+			log.Printf("Failed to export tasks from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to export tasks from DB: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Failed to export tasks: %v", err)
+		w.Header().Set("X-Export-Error", err.Error())
+		return
+	}
+
+	if truncated {
+		w.Header().Set("X-Export-Truncated", "true")
+	}
+}
@@ -1,33 +1,110 @@
 package handler
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"restapi/audit"
 	"restapi/auth"
 	"restapi/db"
 	"restapi/middleware"
+	"restapi/project"
 	"restapi/task"
 	"restapi/user"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
 type Handler struct {
-	DB    TaskStore
-	Cache TaskCache
+	DB       TaskStore
+	Cache    TaskCache
+	Blobs    BlobStore
+	Workflow *task.Workflow
+
+	DeleteMode        task.DeleteMode
+	TrashRetention    time.Duration
+	PurgeInterval     time.Duration
+	MaxAttachmentSize int64
+	AttachmentTypes   map[string]bool
+	ExportMaxRows     int
+	PublicURL         string
 }
 
-func NewHandler(s TaskStore, c TaskCache) (*Handler, error) {
+func NewHandler(s TaskStore, c TaskCache, b BlobStore) (*Handler, error) {
+	wf := task.DefaultWorkflow()
+	if cfg := os.Getenv("TASK_WORKFLOW"); cfg != "" {
+		var err error
+		wf, err = task.ParseWorkflow([]byte(cfg))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deleteMode := task.DeleteForbid
+	if cfg := os.Getenv("TASK_DELETE_MODE"); cfg != "" {
+		var err error
+		deleteMode, err = task.ParseDeleteMode(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	retention, err := envDuration("TRASH_RETENTION", defaultTrashRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	purgeInterval, err := envDuration("TRASH_PURGE_INTERVAL", defaultPurgeInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	maxSize := int64(defaultMaxAttachmentSize)
+	if cfg := os.Getenv("ATTACHMENT_MAX_SIZE"); cfg != "" {
+		var err error
+		maxSize, err = strconv.ParseInt(cfg, 10, 64)
+		if err != nil || maxSize <= 0 {
+			return nil, fmt.Errorf("invalid ATTACHMENT_MAX_SIZE: %q", cfg)
+		}
+	}
+
+	types := defaultAttachmentTypes
+	if cfg := os.Getenv("ATTACHMENT_TYPES"); cfg != "" {
+		types = strings.Split(cfg, ",")
+	}
+	allowedTypes := make(map[string]bool, len(types))
+	for _, t := range types {
+		allowedTypes[strings.TrimSpace(t)] = true
+	}
+
+	exportMaxRows := defaultExportMaxRows
+	if cfg := os.Getenv("EXPORT_MAX_ROWS"); cfg != "" {
+		var err error
+		exportMaxRows, err = strconv.Atoi(cfg)
+		if err != nil || exportMaxRows <= 0 {
+			return nil, fmt.Errorf("invalid EXPORT_MAX_ROWS: %q", cfg)
+		}
+	}
+
 	return &Handler{
-		DB:    s,
-		Cache: c,
+		DB:                s,
+		Cache:             c,
+		Blobs:             b,
+		Workflow:          wf,
+		DeleteMode:        deleteMode,
+		TrashRetention:    retention,
+		PurgeInterval:     purgeInterval,
+		MaxAttachmentSize: maxSize,
+		AttachmentTypes:   allowedTypes,
+		ExportMaxRows:     exportMaxRows,
+		PublicURL:         strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
 	}, nil
 }
 
@@ -53,9 +130,7 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Authorization", "Bearer "+token)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
+	render(w, r, http.StatusCreated, map[string]string{
 		"token": token,
 	})
 }
@@ -90,14 +165,76 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Authorization", "Bearer "+token)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
+	render(w, r, http.StatusCreated, map[string]string{
 		"token": token,
 	})
 }
 
+func userIDFromContext(r *http.Request) (int, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	return userID, ok
+}
+
+func auditMeta(r *http.Request, userID int) audit.Meta {
+	requestID, _ := r.Context().Value(middleware.RequestIDKey).(string)
+	return audit.Meta{Actor: userID, RequestID: requestID}
+}
+
+// statusError is a failed check together with the HTTP status it maps to.
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+func (e *statusError) write(w http.ResponseWriter) {
+	http.Error(w, e.msg, e.status)
+}
+
+func (h *Handler) checkTask(taskID, userID int, need task.Access) *statusError {
+	access, err := h.DB.GetTaskAccess(taskID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			return &statusError{http.StatusNotFound, fmt.Sprintf("Task %d not found", taskID)}
+		}
+		log.Printf("Failed to check access to task: %v", err)
+		return &statusError{http.StatusInternalServerError, fmt.Sprintf("Failed to check access to task: %v", err)}
+	}
+
+	if access == task.AccessNone {
+		return &statusError{http.StatusNotFound, fmt.Sprintf("Task %d not found", taskID)}
+	}
+	if access < need {
+		return &statusError{http.StatusForbidden, fmt.Sprintf("Access to task %d denied", taskID)}
+	}
+
+	return nil
+}
+
+func (h *Handler) authorizeTask(w http.ResponseWriter, taskID, userID int, need task.Access) bool {
+	if err := h.checkTask(taskID, userID, need); err != nil {
+		err.write(w)
+		return false
+	}
+	return true
+}
+
+func validTask(t *task.Task) bool {
+	return t.Name != "" && t.Description != "" && len(t.Description) <= task.MaxDescriptionLength &&
+		(t.Priority == nil || *t.Priority >= 0) &&
+		(t.Language == "" || task.Languages[t.Language])
+}
+
 func (h *Handler) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	var t task.Task
 
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
@@ -106,30 +243,59 @@ func (h *Handler) CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	if t.Name == "" || t.Description == "" {
+	if !validTask(&t) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	insertedTask, err := h.DB.AddTask(&t)
+	t.Owner = userID
+	t.Status = h.Workflow.Initial
+	if t.Language == "" {
+		t.Language = task.DefaultLanguage
+	}
+
+	if t.ProjectID != nil && !h.authorizeProject(w, *t.ProjectID, userID, project.RoleEditor) {
+		return
+	}
+
+	if t.ParentID != nil && !h.authorizeTask(w, *t.ParentID, userID, task.AccessWrite) {
+		return
+	}
+
+	insertedTask, err := h.DB.AddTask(&t, auditMeta(r, userID))
 	if err != nil {
 		log.Printf("Failed to insert task into DB: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to insert task into DB: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(insertedTask)
+	w.Header().Set("ETag", taskETag(insertedTask.Version))
+	render(w, r, http.StatusCreated, insertedTask)
 }
 
 func (h *Handler) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
+	latestComments, err := parseCommentsOption(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
 	task, err := h.Cache.Get(id)
 	if err != nil {
 		log.Printf("Failed to get from cache: %v", err)
@@ -152,86 +318,131 @@ func (h *Handler) GetTaskHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(task)
+	if latestComments != nil {
+		task.Comments = nil
+		if *latestComments > 0 {
+			comments, _, err := h.DB.GetComments(id, *latestComments, 0, true)
+			if err != nil {
+				log.Printf("Failed to get comments from DB: %v", err)
+				http.Error(w, fmt.Sprintf("Failed to get comments from DB: %v", err), http.StatusInternalServerError)
+				return
+			}
+			task.Comments, _ = json.Marshal(comments)
+		}
+	}
+
+	w.Header().Set("ETag", taskETag(task.Version))
+	render(w, r, http.StatusOK, task)
 }
 
-type GetSelectedTasksRequest struct {
-	Name    string `json:"name,omitempty"`
-	OrderBy string `json:"order_by,omitempty"`
-	Sort    string `json:"sort,omitempty"`
-	Limit   *int   `json:"limit,omitempty"`
-	Format  string `json:"format,omitempty"`
+// selectedTasksFilter validates a task query and turns it into a filter.
+func (h *Handler) selectedTasksFilter(userID int, req *GetSelectedTasksRequest) (*task.Filter, *statusError) {
+	if req.ProjectID != nil {
+		if err := h.checkProject(*req.ProjectID, userID, project.RoleViewer); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Language == "" {
+		req.Language = task.DefaultLanguage
+	}
+	if !task.Languages[req.Language] {
+		return nil, &statusError{http.StatusBadRequest, fmt.Sprintf("Unsupported language: %s", req.Language)}
+	}
+
+	for _, s := range req.Status {
+		if !h.Workflow.Known(s) {
+			return nil, &statusError{http.StatusBadRequest, fmt.Sprintf("Unknown status: %s", s)}
+		}
+	}
+
+	return &task.Filter{
+		UserID:         userID,
+		Name:           req.Name,
+		ProjectID:      req.ProjectID,
+		Statuses:       req.Status,
+		AssigneeID:     req.AssigneeID,
+		DueBefore:      req.DueBefore,
+		DueAfter:       req.DueAfter,
+		Overdue:        req.Overdue,
+		Ready:          req.Ready,
+		Closed:         h.Workflow.Closed,
+		MinPriority:    req.Priority,
+		LabelsAny:      req.LabelsAny,
+		LabelsAll:      req.LabelsAll,
+		Search:         req.Search,
+		SearchLanguage: req.Language,
+		Sort:           req.Sort,
+		Limit:          req.Limit,
+		Cursor:         req.Cursor,
+		WithTotal:      req.WithTotal,
+		LatestComments: req.Comments,
+		Expr:           req.Filter,
+	}, nil
 }
 
 func (h *Handler) GetSelectedTasksHandler(w http.ResponseWriter, r *http.Request) {
-	var selectedTasksReq GetSelectedTasksRequest
-	json.NewDecoder(r.Body).Decode(&selectedTasksReq)
-	defer r.Body.Close()
-	fmt.Println(selectedTasksReq)
-
-	tasks, err := h.DB.GetSelectedTasks(
-		selectedTasksReq.Name,
-		selectedTasksReq.OrderBy,
-		selectedTasksReq.Sort,
-		selectedTasksReq.Limit,
-	)
-	if err != nil {
-		log.Printf("Failed to get selected tasks from DB: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to get selected tasks from DB: %v", err), http.StatusInternalServerError)
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
 		return
 	}
 
-	if selectedTasksReq.Format == "" {
-		selectedTasksReq.Format = "json"
+	selectedTasksReq, err := parseSelectedTasksRequest(r.URL.Query())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
 	}
 
-	switch strings.ToLower(selectedTasksReq.Format) {
-	case "json":
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(tasks); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to encode JSON: %v", err), http.StatusInternalServerError)
-		}
-	case "csv":
-		w.Header().Set("Content-Type", "text/csv")
-		w.Header().Set("Content-Disposition", "attachment;filename=tasks.csv")
-		w.WriteHeader(http.StatusOK)
-
-		csvWriter := csv.NewWriter(w)
-		defer csvWriter.Flush()
+	f, ferr := h.selectedTasksFilter(userID, selectedTasksReq)
+	if ferr != nil {
+		ferr.write(w)
+		return
+	}
 
-		if err := csvWriter.Write([]string{"ID", "Name", "Description"}); err != nil {
-			http.Error(w, fmt.Sprintf("Failed to write CSV header: %v", err), http.StatusInternalServerError)
+	page, err := h.DB.GetSelectedTasks(f)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
 			return
 		}
+		log.Printf("Failed to get selected tasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get selected tasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-		for _, t := range tasks {
-			record := []string{
-				strconv.Itoa(t.ID),
-				t.Name,
-				t.Description,
-			}
-			if err := csvWriter.Write(record); err != nil {
-				http.Error(w, fmt.Sprintf("Failed to write CSV row: %v", err), http.StatusInternalServerError)
-				return
-			}
-		}
-	default:
-		http.Error(w, "Unsupported format: "+selectedTasksReq.Format, http.StatusBadRequest)
+	if page.NextCursor != "" {
+		w.Header().Set("X-Next-Cursor", page.NextCursor)
+	}
+	if page.PrevCursor != "" {
+		w.Header().Set("X-Prev-Cursor", page.PrevCursor)
 	}
+	if page.Total != nil {
+		w.Header().Set("X-Total-Count", strconv.Itoa(*page.Total))
+	}
+
+	render(w, r, http.StatusOK, page.Tasks)
 }
 
 func (h *Handler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var t task.Task
 
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 		return
@@ -240,15 +451,17 @@ func (h *Handler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 
 	t.ID = id
 
-	if t.ID == 0 || t.Name == "" || t.Description == "" {
+	if t.ID == 0 || !validTask(&t) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	updatedTask, err := h.DB.UpdateTask(&t)
+	updatedTask, err := h.DB.UpdateTask(&t, parseIfMatch(r), auditMeta(r, userID))
 	if err != nil {
 		if errors.Is(err, db.ErrTaskNotFound) {
 			http.Error(w, fmt.Sprintf("Task %d not found", t.ID), http.StatusNotFound)
+		} else if errors.Is(err, db.ErrVersionConflict) {
+			http.Error(w, fmt.Sprintf("Task %d was modified, reload it and retry", t.ID), http.StatusPreconditionFailed)
 		} else {
 			log.Printf("Failed to update task in DB: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to update task in DB: %v", err), http.StatusInternalServerError)
@@ -260,22 +473,43 @@ func (h *Handler) UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Failed to delete from cache: %v", err)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedTask)
+	w.Header().Set("ETag", taskETag(updatedTask.Version))
+	render(w, r, http.StatusOK, updatedTask)
 }
 
 func (h *Handler) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		http.Error(w, "Invalid task ID", http.StatusBadRequest)
 		return
 	}
 
-	err = h.DB.DeleteTask(id)
+	mode := h.DeleteMode
+	if s := r.URL.Query().Get("subtasks"); s != "" {
+		if mode, err = task.ParseDeleteMode(s); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessOwner) {
+		return
+	}
+
+	ids, err := h.DB.DeleteTask(id, mode, parseIfMatch(r), auditMeta(r, userID))
 	if err != nil {
 		if errors.Is(err, db.ErrTaskNotFound) {
 			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		} else if errors.Is(err, db.ErrVersionConflict) {
+			http.Error(w, fmt.Sprintf("Task %d was modified, reload it and retry", id), http.StatusPreconditionFailed)
+		} else if errors.Is(err, db.ErrTaskHasSubtasks) {
+			http.Error(w, fmt.Sprintf("Task %d has subtasks, use subtasks=cascade or subtasks=orphan", id), http.StatusConflict)
 		} else {
 			log.Printf("Failed to delete task from DB: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to delete from DB: %v", err), http.StatusInternalServerError)
@@ -283,15 +517,17 @@ func (h *Handler) DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err = h.Cache.Delete(id); err != nil {
-		log.Printf("Failed to delete from cache: %v", err)
+	for _, taskID := range ids {
+		if err = h.Cache.Delete(taskID); err != nil {
+			log.Printf("Failed to delete from cache: %v", err)
+		}
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *Handler) AddCommentToTaskHandler(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	userID, ok := userIDFromContext(r)
 	if !ok {
 		http.Error(w, "User not found in context", http.StatusUnauthorized)
 		return
@@ -303,8 +539,13 @@ func (h *Handler) AddCommentToTaskHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
 	var t struct {
-		Text string `json:"text"`
+		Text     string `json:"text"`
+		ParentID *int   `json:"parent_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
 		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
@@ -312,10 +553,17 @@ func (h *Handler) AddCommentToTaskHandler(w http.ResponseWriter, r *http.Request
 	}
 	defer r.Body.Close()
 
-	comment, err := h.DB.AddComment(id, userID, t.Text)
+	if t.Text == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.DB.AddComment(id, userID, t.ParentID, t.Text, auditMeta(r, userID))
 	if err != nil {
 		if errors.Is(err, db.ErrTaskNotFound) {
 			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		} else if errors.Is(err, db.ErrCommentNotFound) {
+			http.Error(w, fmt.Sprintf("Parent comment %d not found in task %d", *t.ParentID, id), http.StatusBadRequest)
 		} else {
 			log.Printf("Failed to get task from DB: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to get task from DB: %v", err), http.StatusInternalServerError)
@@ -323,7 +571,9 @@ func (h *Handler) AddCommentToTaskHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(comment)
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	render(w, r, http.StatusOK, comment)
 }
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetSubtasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	tasks, err := h.DB.GetSubtasks(id, userID)
+	if err != nil {
+		log.Printf("Failed to get subtasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get subtasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, tasks)
+}
+
+func (h *Handler) GetSubtreeHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	tasks, err := h.DB.GetSubtree(id, userID)
+	if err != nil {
+		log.Printf("Failed to get subtree from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get subtree from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, tasks)
+}
+
+func (h *Handler) GetProgressHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	progress, err := h.DB.GetProgress(id, h.Workflow.Closed)
+	if err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to get task progress from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to get task progress from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render(w, r, http.StatusOK, progress)
+}
+
+func (h *Handler) MoveTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		ParentID *int `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	if req.ParentID != nil && !h.authorizeTask(w, *req.ParentID, userID, task.AccessWrite) {
+		return
+	}
+
+	movedTask, err := h.DB.MoveTask(id, req.ParentID, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrTaskCycle) {
+			http.Error(w, fmt.Sprintf("Task %d cannot be moved under its own subtask %d", id, *req.ParentID), http.StatusConflict)
+		} else if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to move task in DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to move task in DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	render(w, r, http.StatusOK, movedTask)
+}
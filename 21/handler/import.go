@@ -0,0 +1,279 @@
+package handler
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"restapi/task"
+)
+
+const (
+	importChunkSize = 100
+	maxImportErrors = 1000
+)
+
+type importError struct {
+	Line  int    `json:"line"`
+	ID    int    `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+type importSummary struct {
+	DryRun          bool          `json:"dry_run"`
+	Rows            int           `json:"rows"`
+	Created         int           `json:"created"`
+	Updated         int           `json:"updated"`
+	Failed          int           `json:"failed"`
+	Errors          []importError `json:"errors"`
+	ErrorsTruncated bool          `json:"errors_truncated,omitempty"`
+
+	// Error is set when the file could not be read to the end. Rows up to
+	// LastCommittedLine were applied and the rest of the file was not.
+	Error             string `json:"error,omitempty"`
+	LastCommittedLine int    `json:"last_committed_line,omitempty"`
+}
+
+func (s *importSummary) fail(line, id int, msg string) {
+	s.Failed++
+	if len(s.Errors) == maxImportErrors {
+		s.ErrorsTruncated = true
+		return
+	}
+	s.Errors = append(s.Errors, importError{Line: line, ID: id, Error: msg})
+}
+
+func (s *importSummary) succeed(op task.BatchOpKind) {
+	if op == task.BatchCreate {
+		s.Created++
+	} else {
+		s.Updated++
+	}
+}
+
+// taskImport collects valid rows and applies them in chunks, so only one
+// chunk is held in memory no matter how large the file is.
+type taskImport struct {
+	h        *Handler
+	r        *http.Request
+	userID   int
+	dryRun   bool
+	summary  importSummary
+	ops      []task.BatchOp
+	lines    []int
+	projects map[int]*statusError
+}
+
+func (imp *taskImport) add(line int, op task.BatchOp) error {
+	if err := imp.h.prepareBatchOp(imp.userID, &op, imp.projects); err != nil {
+		imp.summary.fail(line, op.ID, err.msg)
+		return nil
+	}
+
+	if imp.dryRun {
+		imp.summary.succeed(op.Op)
+		return nil
+	}
+
+	imp.ops = append(imp.ops, op)
+	imp.lines = append(imp.lines, line)
+	if len(imp.ops) < importChunkSize {
+		return nil
+	}
+	return imp.flush()
+}
+
+func (imp *taskImport) flush() error {
+	if len(imp.ops) == 0 {
+		return nil
+	}
+
+	outcomes, err := imp.h.DB.RunBatch(imp.ops, false, auditMeta(imp.r, imp.userID))
+	if err != nil {
+		return err
+	}
+
+	for i, o := range outcomes {
+		if o.Err != nil {
+			_, msg := batchOutcomeStatus(&imp.ops[i], o.Err)
+			imp.summary.fail(imp.lines[i], imp.ops[i].ID, msg)
+			continue
+		}
+
+		imp.summary.succeed(imp.ops[i].Op)
+		for _, id := range o.Touched {
+			if err := imp.h.Cache.Delete(id); err != nil {
+				log.Printf("Failed to delete from cache: %v", err)
+			}
+		}
+	}
+
+	imp.summary.LastCommittedLine = imp.lines[len(imp.lines)-1]
+	imp.ops = imp.ops[:0]
+	imp.lines = imp.lines[:0]
+	return nil
+}
+
+// csvImportBody returns the uploaded file, sent either as the whole body or
+// as the "file" field of a multipart form.
+func csvImportBody(r *http.Request) (io.Reader, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("missing file field")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// ImportTasksHandler reads tasks from a CSV file with the columns of the CSV
+// export. In the default create mode every row becomes a new task; with
+// mode=upsert rows with an ID update that task instead, overwriting its name,
+// description, due date and priority. Owner and Status are ignored: new tasks
+// belong to the caller and start in the initial status. Project and Labels
+// are only applied to new tasks. With dry_run=true rows are validated and
+// access is checked, but nothing is written.
+//
+// Rows are committed in chunks, so a file that cannot be read to the end,
+// because the client went away or the encoding is broken, still applies the
+// rows before the failure. The response is then a 400 with the summary, its
+// error and the last committed line, so the client can resume after it.
+func (h *Handler) ImportTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+
+	mode := q.Get("mode")
+	if mode != "" && mode != "create" && mode != "upsert" {
+		http.Error(w, "Invalid mode, expected create or upsert", http.StatusBadRequest)
+		return
+	}
+
+	dryRun, err := queryBool(q, "dry_run")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	delimiter, err := parseCSVDelimiter(q.Get("delimiter"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	defer r.Body.Close()
+	body, err := csvImportBody(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	decoded, err := csvDecoder(body, q.Get("encoding"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	reader := csv.NewReader(decoded)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		http.Error(w, "Empty CSV file", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid CSV header: %v", err), http.StatusBadRequest)
+		return
+	}
+	columns, err := taskCSVColumns(header)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid CSV header: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	imp := &taskImport{
+		h:        h,
+		r:        r,
+		userID:   userID,
+		dryRun:   dryRun,
+		summary:  importSummary{DryRun: dryRun, Errors: []importError{}},
+		projects: make(map[int]*statusError),
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			imp.summary.Rows++
+			imp.summary.fail(parseErr.StartLine, 0, parseErr.Err.Error())
+			continue
+		}
+		if err != nil {
+			if err := imp.flush(); err != nil {
+				log.Printf("Failed to import tasks: %v", err)
+				http.Error(w, fmt.Sprintf("Failed to import tasks: %v", err), http.StatusInternalServerError)
+				return
+			}
+			imp.summary.Error = fmt.Sprintf("failed to read CSV: %v", err)
+			render(w, r, http.StatusBadRequest, &imp.summary)
+			return
+		}
+
+		imp.summary.Rows++
+		line, _ := reader.FieldPos(0)
+
+		id, t, err := parseTaskCSVRecord(record, columns)
+		if err != nil {
+			imp.summary.fail(line, 0, err.Error())
+			continue
+		}
+
+		op := task.BatchOp{Op: task.BatchCreate, Task: t, Labels: t.Labels}
+		if mode == "upsert" && id != 0 {
+			op = task.BatchOp{Op: task.BatchUpdate, ID: id, Task: t}
+		}
+
+		if err = imp.add(line, op); err != nil {
+			log.Printf("Failed to import tasks: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to import tasks: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err = imp.flush(); err != nil {
+		log.Printf("Failed to import tasks: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to import tasks: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, &imp.summary)
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+func validLabel(label string) bool {
+	return label != "" && label == strings.TrimSpace(label) && !strings.Contains(label, ",")
+}
+
+func (h *Handler) AddLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	var req struct {
+		Labels []string `json:"labels"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if len(req.Labels) == 0 {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	for _, l := range req.Labels {
+		if !validLabel(l) {
+			http.Error(w, fmt.Sprintf("Invalid label: %q", l), http.StatusBadRequest)
+			return
+		}
+	}
+
+	labels, err := h.DB.AddLabels(id, req.Labels, auditMeta(r, userID))
+	if err != nil {
+		log.Printf("Failed to add labels to task: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to add labels to task: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	render(w, r, http.StatusOK, labels)
+}
+
+func (h *Handler) RemoveLabelHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	label := mux.Vars(r)["label"]
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	err = h.DB.RemoveLabel(id, label, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrLabelNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d has no label %s", id, label), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to remove label from task: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to remove label from task: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	labels, err := h.DB.GetLabels(userID)
+	if err != nil {
+		log.Printf("Failed to get labels from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get labels from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, labels)
+}
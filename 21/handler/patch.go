@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"restapi/db"
+	"restapi/patch"
+	"restapi/task"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+	maxPatchSize   = 1 << 20
+)
+
+var errInvalidTask = errors.New("invalid task")
+
+// taskPatch holds the task fields a patch may change. Patches touching any
+// other field are rejected.
+type taskPatch struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	Priority    *int       `json:"priority,omitempty"`
+	Language    string     `json:"language,omitempty"`
+}
+
+func applyTaskPatch(t *task.Task, contentType string, body []byte) error {
+	doc, err := json.Marshal(taskPatch{
+		Name:        t.Name,
+		Description: t.Description,
+		DueAt:       t.DueAt,
+		Priority:    t.Priority,
+		Language:    t.Language,
+	})
+	if err != nil {
+		return err
+	}
+
+	if contentType == mergePatchType {
+		doc, err = patch.Merge(doc, body)
+	} else {
+		doc, err = patch.Apply(doc, body)
+	}
+	if err != nil {
+		return err
+	}
+
+	var p taskPatch
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidTask, err)
+	}
+
+	t.Name, t.Description, t.DueAt, t.Priority, t.Language = p.Name, p.Description, p.DueAt, p.Priority, p.Language
+	if !validTask(t) || t.Language == "" {
+		return errInvalidTask
+	}
+	return nil
+}
+
+// PatchTaskHandler applies a JSON Merge Patch or a JSON Patch to the name,
+// description, due_at, priority and language of a task.
+func (h *Handler) PatchTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != mergePatchType && contentType != jsonPatchType {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		http.Error(w, "Unsupported patch format: "+contentType, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	patchedTask, err := h.DB.PatchTask(id, func(t *task.Task) error {
+		return applyTaskPatch(t, contentType, body)
+	}, parseIfMatch(r), auditMeta(r, userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, patch.ErrInvalid):
+			http.Error(w, fmt.Sprintf("Invalid patch: %v", err), http.StatusBadRequest)
+		case errors.Is(err, patch.ErrConflict):
+			http.Error(w, fmt.Sprintf("Failed to apply patch: %v", err), http.StatusConflict)
+		case errors.Is(err, errInvalidTask):
+			http.Error(w, fmt.Sprintf("Patched task is invalid: %v", err), http.StatusUnprocessableEntity)
+		case errors.Is(err, db.ErrTaskNotFound):
+			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		case errors.Is(err, db.ErrVersionConflict):
+			http.Error(w, fmt.Sprintf("Task %d was modified, reload it and retry", id), http.StatusPreconditionFailed)
+		default:
+			log.Printf("Failed to patch task in DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to patch task in DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	w.Header().Set("ETag", taskETag(patchedTask.Version))
+	render(w, r, http.StatusOK, patchedTask)
+}
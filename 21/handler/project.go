@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/project"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) checkProject(projectID, userID int, need project.Role) *statusError {
+	role, err := h.DB.GetProjectRole(projectID, userID)
+	if err != nil {
+		if errors.Is(err, db.ErrProjectNotFound) {
+			return &statusError{http.StatusNotFound, fmt.Sprintf("Project %d not found", projectID)}
+		}
+		log.Printf("Failed to check role in project: %v", err)
+		return &statusError{http.StatusInternalServerError, fmt.Sprintf("Failed to check role in project: %v", err)}
+	}
+
+	if role == "" {
+		return &statusError{http.StatusNotFound, fmt.Sprintf("Project %d not found", projectID)}
+	}
+	if role.Access() < need.Access() {
+		return &statusError{http.StatusForbidden, fmt.Sprintf("Access to project %d denied", projectID)}
+	}
+
+	return nil
+}
+
+func (h *Handler) authorizeProject(w http.ResponseWriter, projectID, userID int, need project.Role) bool {
+	if err := h.checkProject(projectID, userID, need); err != nil {
+		err.write(w)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) CreateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	var p project.Project
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if p.Name == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	p.Owner = userID
+
+	insertedProject, err := h.DB.AddProject(&p, auditMeta(r, userID))
+	if err != nil {
+		log.Printf("Failed to insert project into DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to insert project into DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusCreated, insertedProject)
+}
+
+func (h *Handler) GetProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	projects, err := h.DB.GetProjects(userID)
+	if err != nil {
+		log.Printf("Failed to get projects from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get projects from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, projects)
+}
+
+func (h *Handler) AddProjectMemberHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeProject(w, id, userID, project.RoleOwner) {
+		return
+	}
+
+	var req struct {
+		Login string       `json:"login"`
+		Role  project.Role `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Role == "" {
+		req.Role = project.RoleViewer
+	}
+	if req.Login == "" || !req.Role.Valid() || req.Role == project.RoleOwner {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	member, err := h.DB.AddProjectMember(id, req.Login, req.Role, auditMeta(r, userID))
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrUserNotFound):
+			http.Error(w, fmt.Sprintf("User %s not found", req.Login), http.StatusNotFound)
+		case errors.Is(err, db.ErrProjectOwner):
+			http.Error(w, "Project owner role cannot be changed", http.StatusConflict)
+		default:
+			log.Printf("Failed to add member to project: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to add member to project: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	render(w, r, http.StatusCreated, member)
+}
+
+func (h *Handler) GetProjectMembersHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeProject(w, id, userID, project.RoleViewer) {
+		return
+	}
+
+	members, err := h.DB.GetProjectMembers(id)
+	if err != nil {
+		log.Printf("Failed to get project members from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get project members from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, members)
+}
+
+func (h *Handler) GetProjectTasksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeProject(w, id, userID, project.RoleViewer) {
+		return
+	}
+
+	page, err := h.DB.GetSelectedTasks(&task.Filter{
+		UserID:    userID,
+		ProjectID: &id,
+	})
+	if err != nil {
+		log.Printf("Failed to get project tasks from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get project tasks from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, page.Tasks)
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"restapi/filter"
+	"restapi/task"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GetSelectedTasksRequest struct {
+	Name       string
+	ProjectID  *int
+	Status     []task.Status
+	AssigneeID *int
+	DueBefore  *time.Time
+	DueAfter   *time.Time
+	Overdue    bool
+	Ready      bool
+	Priority   *int
+	LabelsAny  []string
+	LabelsAll  []string
+	Search     string
+	Language   string
+	Filter     filter.Expr
+	Sort       []task.SortKey
+	Limit      *int
+	Cursor     string
+	Comments   *int
+	WithTotal  bool
+}
+
+func parseSelectedTasksRequest(q url.Values) (*GetSelectedTasksRequest, error) {
+	req := &GetSelectedTasksRequest{
+		Name:     q.Get("name"),
+		Search:   q.Get("search"),
+		Language: q.Get("language"),
+		Cursor:   q.Get("cursor"),
+	}
+
+	var err error
+	if req.Sort, err = task.ParseSort(q.Get("order_by"), strings.ToLower(q.Get("sort")) == "desc"); err != nil {
+		return nil, err
+	}
+	if req.ProjectID, err = queryInt(q, "project_id"); err != nil {
+		return nil, err
+	}
+	if req.AssigneeID, err = queryInt(q, "assignee_id"); err != nil {
+		return nil, err
+	}
+	if req.Priority, err = queryInt(q, "priority"); err != nil {
+		return nil, err
+	}
+	if req.Limit, err = queryInt(q, "limit"); err != nil {
+		return nil, err
+	}
+	if req.Limit != nil && *req.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if req.DueBefore, err = queryTime(q, "due_before"); err != nil {
+		return nil, err
+	}
+	if req.DueAfter, err = queryTime(q, "due_after"); err != nil {
+		return nil, err
+	}
+	if req.Overdue, err = queryBool(q, "overdue"); err != nil {
+		return nil, err
+	}
+	if req.Ready, err = queryBool(q, "ready"); err != nil {
+		return nil, err
+	}
+	if req.WithTotal, err = queryBool(q, "with_total"); err != nil {
+		return nil, err
+	}
+
+	for _, s := range queryList(q, "status") {
+		req.Status = append(req.Status, task.Status(s))
+	}
+	if req.Comments, err = parseCommentsOption(q); err != nil {
+		return nil, err
+	}
+
+	req.LabelsAny = queryList(q, "labels_any")
+	req.LabelsAll = queryList(q, "labels_all")
+
+	var exprs []filter.Expr
+	for _, s := range q["filter"] {
+		e, err := filter.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+	switch len(exprs) {
+	case 0:
+	case 1:
+		req.Filter = exprs[0]
+	default:
+		req.Filter = &filter.Group{Exprs: exprs}
+	}
+
+	return req, nil
+}
+
+// parseCommentsOption reads the comments parameter: "all" (the default) keeps
+// the whole thread, "count" leaves only comment_count and a number N returns
+// the latest N comments.
+func parseCommentsOption(q url.Values) (*int, error) {
+	switch s := q.Get("comments"); s {
+	case "", "all":
+		return nil, nil
+	case "count":
+		n := 0
+		return &n, nil
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid comments: %q, expected all, count or a positive number", s)
+		}
+		return &n, nil
+	}
+}
+
+func queryInt(q url.Values, key string) (*int, error) {
+	s := q.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %q", key, s)
+	}
+	return &v, nil
+}
+
+func queryTime(q url.Values, key string) (*time.Time, error) {
+	s := q.Get(key)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %q, expected RFC 3339", key, s)
+	}
+	return &v, nil
+}
+
+func queryBool(q url.Values, key string) (bool, error) {
+	s := q.Get(key)
+	if s == "" {
+		return false, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %q", key, s)
+	}
+	return v, nil
+}
+
+// queryList accepts both repeated keys and comma-separated values.
+func queryList(q url.Values, key string) []string {
+	var list []string
+	for _, s := range q[key] {
+		for _, v := range strings.Split(s, ",") {
+			if v != "" {
+				list = append(list, v)
+			}
+		}
+	}
+	return list
+}
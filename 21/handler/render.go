@@ -0,0 +1,473 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"restapi/task"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	mediaJSON   = "application/json"
+	mediaCSV    = "text/csv"
+	mediaNDJSON = "application/x-ndjson"
+	mediaXML    = "application/xml"
+	mediaYAML   = "application/yaml"
+)
+
+// formatMediaTypes maps the format query parameter, which overrides the
+// Accept header, to media types.
+var formatMediaTypes = map[string]string{
+	"json":   mediaJSON,
+	"csv":    mediaCSV,
+	"ndjson": mediaNDJSON,
+	"xml":    mediaXML,
+	"yaml":   mediaYAML,
+}
+
+// mediaAliases lists other names clients use for the supported types.
+var mediaAliases = map[string]string{
+	"application/csv":    mediaCSV,
+	"application/ndjson": mediaNDJSON,
+	"application/jsonl":  mediaNDJSON,
+	"text/xml":           mediaXML,
+	"application/x-yaml": mediaYAML,
+	"text/yaml":          mediaYAML,
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, entry := range strings.Split(header, ",") {
+		params := strings.Split(entry, ";")
+		mt := strings.ToLower(strings.TrimSpace(params[0]))
+		if canonical, ok := mediaAliases[mt]; ok {
+			mt = canonical
+		}
+		typ, subtype, ok := strings.Cut(mt, "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, p := range params[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(p), "=")
+			if strings.ToLower(k) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil || q < 0 || q > 1 {
+				ok = false
+			}
+			r.q = q
+		}
+		if ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// acceptQuality returns the quality the client gives to mt, taken from the
+// most specific range matching it.
+func acceptQuality(ranges []mediaRange, mt string) float64 {
+	typ, subtype, _ := strings.Cut(mt, "/")
+	q, specificity := 0.0, 0
+	for _, r := range ranges {
+		s := 0
+		switch {
+		case r.typ == typ && r.subtype == subtype:
+			s = 3
+		case r.typ == typ && r.subtype == "*":
+			s = 2
+		case r.typ == "*" && r.subtype == "*":
+			s = 1
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// negotiate picks the offer the client prefers. The format query parameter
+// wins over the Accept header; without either the first offer is used. Ties
+// are broken by the order of offers.
+func negotiate(r *http.Request, offers ...string) (string, *statusError) {
+	notAcceptable := &statusError{
+		http.StatusNotAcceptable,
+		"Not acceptable, available: " + strings.Join(offers, ", "),
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" {
+		mt, ok := formatMediaTypes[strings.ToLower(format)]
+		if !ok {
+			return "", &statusError{http.StatusBadRequest, "Unsupported format: " + format}
+		}
+		for _, o := range offers {
+			if o == mt {
+				return mt, nil
+			}
+		}
+		return "", notAcceptable
+	}
+
+	header := strings.Join(r.Header.Values("Accept"), ",")
+	if strings.TrimSpace(header) == "" {
+		return offers[0], nil
+	}
+
+	ranges := parseAccept(header)
+	best, bestQ := "", 0.0
+	for _, o := range offers {
+		if q := acceptQuality(ranges, o); q > bestQ {
+			best, bestQ = o, q
+		}
+	}
+	if best == "" {
+		return "", notAcceptable
+	}
+	return best, nil
+}
+
+// csvTable returns the CSV form of the values that have one.
+func csvTable(v interface{}) (string, []string, [][]string, bool) {
+	switch v := v.(type) {
+	case *task.Task:
+		return "task.csv", taskCSVHeader, [][]string{taskCSVRecord(v)}, true
+	case []task.Task:
+		records := make([][]string, len(v))
+		for i := range v {
+			records[i] = taskCSVRecord(&v[i])
+		}
+		return "tasks.csv", taskCSVHeader, records, true
+	case *task.Comment:
+		return "comment.csv", commentCSVHeader, [][]string{commentCSVRecord(v)}, true
+	case []task.Comment:
+		records := make([][]string, len(v))
+		for i := range v {
+			records[i] = commentCSVRecord(&v[i])
+		}
+		return "comments.csv", commentCSVHeader, records, true
+	}
+	return "", nil, nil, false
+}
+
+// render writes v with the given status in the format negotiated from the
+// request. JSON, NDJSON, XML and YAML are available for every response and
+// CSV for tasks and comments. When no format is acceptable a GET gets 406,
+// while other methods fall back to JSON since their change is already made.
+func render(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	offers := []string{mediaJSON, mediaNDJSON, mediaXML, mediaYAML}
+	name, header, records, tabular := csvTable(v)
+	if tabular {
+		offers = append(offers, mediaCSV)
+	}
+
+	mt, err := negotiate(r, offers...)
+	if err != nil {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			mt = mediaJSON
+		} else {
+			err.write(w)
+			return
+		}
+	}
+
+	var body bytes.Buffer
+	var encodeErr error
+	switch mt {
+	case mediaJSON:
+		encodeErr = json.NewEncoder(&body).Encode(v)
+	case mediaNDJSON:
+		encodeErr = writeNDJSON(&body, v)
+	case mediaXML:
+		encodeErr = writeXML(&body, v)
+	case mediaYAML:
+		encodeErr = writeYAML(&body, v)
+	case mediaCSV:
+		cw := csv.NewWriter(&body)
+		cw.Write(header)
+		cw.WriteAll(records)
+		encodeErr = cw.Error()
+		w.Header().Set("Content-Disposition", "attachment;filename="+name)
+	}
+	if encodeErr != nil {
+		log.Printf("Failed to encode response as %s: %v", mt, encodeErr)
+		http.Error(w, fmt.Sprintf("Failed to encode response: %v", encodeErr), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", mt)
+	w.WriteHeader(status)
+	w.Write(body.Bytes())
+}
+
+// writeNDJSON writes the elements of a slice one per line, other values are
+// written as a single line.
+func writeNDJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return enc.Encode(v)
+	}
+	for i := 0; i < rv.Len(); i++ {
+		if err := enc.Encode(rv.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonObject keeps the fields of a JSON object in order, so XML and YAML list
+// them as JSON does.
+type jsonObject []jsonField
+
+type jsonField struct {
+	Key   string
+	Value interface{}
+}
+
+// toJSONTree converts v to the generic values of its JSON form: jsonObject,
+// []interface{}, json.Number, string, bool or nil. XML and YAML are rendered
+// from it, so field names and omitted fields match the JSON responses.
+func toJSONTree(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return decodeJSONTree(dec)
+}
+
+func decodeJSONTree(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := jsonObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, jsonField{Key: key.(string), Value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			value, err := decodeJSONTree(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// writeXML renders v under a <response> element. Object fields become
+// elements named after their JSON keys and array items become <item>
+// elements; null values are marked with nil="true".
+func writeXML(w io.Writer, v interface{}) error {
+	tree, err := toJSONTree(v)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	xmlElement(&b, "response", tree, 0)
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+func xmlElement(b *bytes.Buffer, name string, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+	open := name
+	if !validXMLName(name) {
+		var attr bytes.Buffer
+		xml.EscapeText(&attr, []byte(name))
+		name, open = "field", `field name="`+attr.String()+`"`
+	}
+
+	switch v := v.(type) {
+	case nil:
+		b.WriteString(pad + "<" + open + ` nil="true"/>` + "\n")
+	case jsonObject:
+		if len(v) == 0 {
+			b.WriteString(pad + "<" + open + "/>\n")
+			return
+		}
+		b.WriteString(pad + "<" + open + ">\n")
+		for _, f := range v {
+			xmlElement(b, f.Key, f.Value, indent+1)
+		}
+		b.WriteString(pad + "</" + name + ">\n")
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(pad + "<" + open + "/>\n")
+			return
+		}
+		b.WriteString(pad + "<" + open + ">\n")
+		for _, item := range v {
+			xmlElement(b, "item", item, indent+1)
+		}
+		b.WriteString(pad + "</" + name + ">\n")
+	default:
+		b.WriteString(pad + "<" + open + ">")
+		xml.EscapeText(b, []byte(fmt.Sprint(v)))
+		b.WriteString("</" + name + ">\n")
+	}
+}
+
+func validXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || unicode.IsLetter(c) || i > 0 && (c == '-' || c == '.' || unicode.IsDigit(c)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// writeYAML renders v as a block style YAML document.
+func writeYAML(w io.Writer, v interface{}) error {
+	tree, err := toJSONTree(v)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	switch tree := tree.(type) {
+	case jsonObject:
+		if len(tree) == 0 {
+			b.WriteString("{}\n")
+		}
+		yamlObject(&b, tree, 0, false)
+	case []interface{}:
+		if len(tree) == 0 {
+			b.WriteString("[]\n")
+		}
+		yamlArray(&b, tree, 0)
+	default:
+		b.WriteString(yamlScalar(tree) + "\n")
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// yamlValue writes v after a "key:" or "-" indicator. Non-empty collections
+// start on the next line at indent.
+func yamlValue(b *bytes.Buffer, v interface{}, indent int) {
+	switch v := v.(type) {
+	case jsonObject:
+		if len(v) == 0 {
+			b.WriteString(" {}\n")
+			return
+		}
+		b.WriteString("\n")
+		yamlObject(b, v, indent, false)
+	case []interface{}:
+		if len(v) == 0 {
+			b.WriteString(" []\n")
+			return
+		}
+		b.WriteString("\n")
+		yamlArray(b, v, indent)
+	default:
+		b.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+// yamlObject writes the fields of o at indent. With inline set the first field
+// continues the current line, right after a "- ".
+func yamlObject(b *bytes.Buffer, o jsonObject, indent int, inline bool) {
+	for i, f := range o {
+		if i > 0 || !inline {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		b.WriteString(yamlString(f.Key) + ":")
+		yamlValue(b, f.Value, indent+2)
+	}
+}
+
+func yamlArray(b *bytes.Buffer, a []interface{}, indent int) {
+	for _, item := range a {
+		b.WriteString(strings.Repeat(" ", indent) + "-")
+		if o, ok := item.(jsonObject); ok && len(o) > 0 {
+			b.WriteString(" ")
+			yamlObject(b, o, indent+2, true)
+			continue
+		}
+		yamlValue(b, item, indent+2)
+	}
+}
+
+func yamlScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	}
+	return yamlString(fmt.Sprint(v))
+}
+
+// yamlReserved are plain scalars YAML 1.1 parsers read as booleans or null.
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// yamlString leaves simple words unquoted and double-quotes everything else,
+// using escapes YAML shares with Go.
+func yamlString(s string) string {
+	plain := s != "" && !yamlReserved[strings.ToLower(s)] && !strings.HasSuffix(s, " ")
+	for i, c := range s {
+		if !plain {
+			break
+		}
+		plain = unicode.IsLetter(c) || c == '_' ||
+			i > 0 && (unicode.IsDigit(c) || c == ' ' || c == '-' || c == '.' || c == '/')
+	}
+	if plain {
+		return s
+	}
+	return strconv.Quote(s)
+}
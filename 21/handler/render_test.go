@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	all := []string{mediaJSON, mediaNDJSON, mediaXML, mediaYAML, mediaCSV}
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		offers []string
+		want   string
+		status int
+	}{
+		{"no header", "", "", all, mediaJSON, 0},
+		{"exact", "", "text/csv", all, mediaCSV, 0},
+		{"alias", "", "text/yaml", all, mediaYAML, 0},
+		{"case insensitive", "", "Application/XML", all, mediaXML, 0},
+		{"quality", "", "application/xml;q=0.5, application/yaml", all, mediaYAML, 0},
+		{"tie keeps offer order", "", "application/xml, application/json", all, mediaJSON, 0},
+		{"subtype wildcard", "", "text/*", all, mediaCSV, 0},
+		{"any", "", "*/*", all, mediaJSON, 0},
+		{"specific range wins", "", "*/*, application/json;q=0.1", all, mediaNDJSON, 0},
+		{"invalid quality dropped", "", "application/json;q=2, application/xml", all, mediaXML, 0},
+		{"refused", "", "application/json;q=0", []string{mediaJSON}, "", http.StatusNotAcceptable},
+		{"unsupported", "", "image/png", all, "", http.StatusNotAcceptable},
+		{"format overrides header", "format=xml", "text/csv", all, mediaXML, 0},
+		{"unknown format", "format=pdf", "", all, "", http.StatusBadRequest},
+		{"format not offered", "format=csv", "", []string{mediaJSON}, "", http.StatusNotAcceptable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/tasks?"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			got, err := negotiate(r, tt.offers...)
+			if tt.status != 0 {
+				if err == nil || err.status != tt.status {
+					t.Fatalf("got %q, %v, want status %d", got, err, tt.status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %d %s", err.status, err.msg)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type renderSample struct {
+	ID     int               `json:"id"`
+	Name   string            `json:"name"`
+	Parent *int              `json:"parent"`
+	Labels []string          `json:"labels"`
+	Extra  map[string]string `json:"extra,omitempty"`
+	Items  []renderItem      `json:"items"`
+}
+
+type renderItem struct {
+	A int `json:"a"`
+	B int `json:"b"`
+}
+
+func TestWriteXML(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"object",
+			renderSample{ID: 1, Name: "a & b", Labels: []string{"x"}, Extra: map[string]string{"1st": "<v>"}, Items: []renderItem{}},
+			`<?xml version="1.0" encoding="UTF-8"?>
+<response>
+  <id>1</id>
+  <name>a &amp; b</name>
+  <parent nil="true"/>
+  <labels>
+    <item>x</item>
+  </labels>
+  <extra>
+    <field name="1st">&lt;v&gt;</field>
+  </extra>
+  <items/>
+</response>
+`},
+		{"empty list", []string{}, `<?xml version="1.0" encoding="UTF-8"?>
+<response/>
+`},
+		{"scalar", "text", `<?xml version="1.0" encoding="UTF-8"?>
+<response>text</response>
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeXML(&b, tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	parent := 7
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"object",
+			renderSample{ID: 1, Name: "yes", Parent: &parent, Labels: []string{}, Items: []renderItem{{1, 2}}},
+			`id: 1
+name: "yes"
+parent: 7
+labels: []
+items:
+  - a: 1
+    b: 2
+`},
+		{"nested list", [][]string{{"a b", "x:"}, nil}, `-
+  - a b
+  - "x:"
+- null
+`},
+		{"empty object", struct{}{}, "{}\n"},
+		{"empty list", []int{}, "[]\n"},
+		{"null", nil, "null\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := writeYAML(&b, tt.v); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"word", "word"},
+		{"two words", "two words"},
+		{"path/to-file.txt", "path/to-file.txt"},
+		{"", `""`},
+		{"No", `"No"`},
+		{"null", `"null"`},
+		{"trailing ", `"trailing "`},
+		{"1st", `"1st"`},
+		{"-dash", `"-dash"`},
+		{"key: value", `"key: value"`},
+		{"line\nbreak", `"line\nbreak"`},
+	}
+
+	for _, tt := range tests {
+		if got := yamlString(tt.in); got != tt.want {
+			t.Errorf("yamlString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
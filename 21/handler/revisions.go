@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) GetRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	revisions, err := h.DB.GetRevisions(id)
+	if err != nil {
+		log.Printf("Failed to get revisions from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get revisions from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, revisions)
+}
+
+// DiffRevisionsHandler compares the revisions given by the from and to query
+// parameters line by line.
+func (h *Handler) DiffRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	from, err := queryInt(q, "from")
+	if err != nil || from == nil {
+		http.Error(w, "Invalid query: from revision is required", http.StatusBadRequest)
+		return
+	}
+	to, err := queryInt(q, "to")
+	if err != nil || to == nil {
+		http.Error(w, "Invalid query: to revision is required", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	revisions := make([]*task.Revision, 2)
+	for i, n := range []int{*from, *to} {
+		revisions[i], err = h.DB.GetRevision(id, n)
+		if err != nil {
+			if errors.Is(err, db.ErrRevisionNotFound) {
+				http.Error(w, fmt.Sprintf("Revision %d of task %d not found", n, id), http.StatusNotFound)
+			} else {
+				log.Printf("Failed to get revision from DB: %v", err)
+				http.Error(w, fmt.Sprintf("Failed to get revision from DB: %v", err), http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	diff, err := task.DiffRevisions(revisions[0], revisions[1])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Revisions are too large to compare, the limit is %d lines", task.MaxDiffLines),
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	render(w, r, http.StatusOK, diff)
+}
+
+func (h *Handler) RevertTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := strconv.Atoi(mux.Vars(r)["revision"])
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	revertedTask, err := h.DB.RevertTask(id, revision, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrRevisionNotFound) {
+			http.Error(w, fmt.Sprintf("Revision %d of task %d not found", revision, id), http.StatusNotFound)
+		} else if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to revert task in DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to revert task in DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	render(w, r, http.StatusOK, revertedTask)
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"restapi/db"
+	"restapi/task"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *Handler) TransitionTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessWrite) {
+		return
+	}
+
+	var req struct {
+		Status task.Status `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	if !h.Workflow.Known(req.Status) {
+		http.Error(w, fmt.Sprintf("Unknown status: %s", req.Status), http.StatusBadRequest)
+		return
+	}
+
+	current, err := h.DB.GetTaskStatus(id)
+	if err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found", id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to get task status from DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to get task status from DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !h.Workflow.CanTransition(current, req.Status) {
+		http.Error(w, fmt.Sprintf("Transition from %s to %s is not allowed, allowed: %v",
+			current, req.Status, h.Workflow.Allowed(current)), http.StatusConflict)
+		return
+	}
+
+	change, err := h.DB.TransitionTask(id, userID, current, req.Status, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrStatusConflict) {
+			http.Error(w, fmt.Sprintf("Status of task %d was changed concurrently", id), http.StatusConflict)
+		} else {
+			log.Printf("Failed to change task status in DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to change task status in DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err = h.Cache.Delete(id); err != nil {
+		log.Printf("Failed to delete from cache: %v", err)
+	}
+
+	render(w, r, http.StatusOK, change)
+}
+
+func (h *Handler) GetStatusHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorizeTask(w, id, userID, task.AccessRead) {
+		return
+	}
+
+	history, err := h.DB.GetStatusHistory(id)
+	if err != nil {
+		log.Printf("Failed to get status history from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get status history from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, history)
+}
+
+func (h *Handler) GetWorkflowHandler(w http.ResponseWriter, r *http.Request) {
+	render(w, r, http.StatusOK, h.Workflow)
+}
@@ -1,17 +1,74 @@
 package handler
 
 import (
+	"context"
+	"restapi/audit"
+	"restapi/project"
 	"restapi/task"
 	"restapi/user"
+	"time"
 )
 
 type TaskStore interface {
-	AddTask(t *task.Task) (*task.Task, error)
+	AddTask(t *task.Task, m audit.Meta) (*task.Task, error)
 	GetTask(id int) (*task.Task, error)
-	GetSelectedTasks(name, orderBy, sort string, limit *int) ([]task.Task, error)
-	UpdateTask(t *task.Task) (*task.Task, error)
-	DeleteTask(id int) error
-	AddComment(taskID, author int, text string) (*task.Comment, error)
+	GetSelectedTasks(f *task.Filter) (*task.Page, error)
+	GetTasksStamp(f *task.Filter) (*task.Stamp, error)
+	StreamSelectedTasks(ctx context.Context, f *task.Filter, fn func([]task.Task) error) (bool, error)
+	UpdateTask(t *task.Task, ifMatch []int, m audit.Meta) (*task.Task, error)
+	PatchTask(taskID int, apply func(*task.Task) error, ifMatch []int, m audit.Meta) (*task.Task, error)
+	RunBatch(ops []task.BatchOp, atomic bool, m audit.Meta) ([]task.BatchOutcome, error)
+	DeleteTask(id int, mode task.DeleteMode, ifMatch []int, m audit.Meta) ([]int, error)
+	GetRevisions(taskID int) ([]task.Revision, error)
+	GetRevision(taskID, revision int) (*task.Revision, error)
+	RevertTask(taskID, revision int, m audit.Meta) (*task.Task, error)
+	GetTrash(userID int) ([]task.TrashedTask, error)
+	RestoreTask(taskID int, m audit.Meta) ([]task.Task, error)
+	PurgeTasks(before time.Time, limit int) ([]int, []string, error)
+	GetSubtasks(taskID, userID int) ([]task.Task, error)
+	GetSubtree(taskID, userID int) ([]task.Subtask, error)
+	GetProgress(taskID int, closed []task.Status) (*task.Progress, error)
+	MoveTask(taskID int, parentID *int, m audit.Meta) (*task.Task, error)
+	AddDependency(taskID, blockerID int, m audit.Meta) (*task.Dependency, error)
+	RemoveDependency(taskID, blockerID int, m audit.Meta) error
+	GetBlockers(taskID, userID int) ([]task.Task, error)
+	GetBlocked(taskID, userID int) ([]task.Task, error)
+	GetDependencies(taskIDs []int) ([]task.Dependency, error)
+	AddAttachment(a *task.Attachment, m audit.Meta) (*task.Attachment, error)
+	GetAttachment(taskID, attachmentID int) (*task.Attachment, error)
+	GetAttachments(taskID int) ([]task.Attachment, error)
+	DeleteAttachment(taskID, attachmentID int, m audit.Meta) (string, error)
+	AddComment(taskID, author int, parentID *int, text string, m audit.Meta) (*task.Comment, error)
+	GetComment(taskID, commentID int) (*task.Comment, error)
+	GetComments(taskID, limit, offset int, desc bool) ([]task.Comment, int, error)
+	UpdateComment(commentID int, text string, m audit.Meta) (*task.Comment, error)
+	DeleteComment(commentID int, m audit.Meta) error
+	GetTaskAccess(taskID, userID int) (task.Access, error)
+	GrantTaskAccess(taskID, userID int, m audit.Meta) error
+	RevokeTaskAccess(taskID, userID int, m audit.Meta) error
+	GetTaskStatus(taskID int) (task.Status, error)
+	TransitionTask(taskID, userID int, from, to task.Status, m audit.Meta) (*task.StatusChange, error)
+	GetStatusHistory(taskID int) ([]task.StatusChange, error)
+	AssignTask(taskID, userID int, m audit.Meta) error
+	UnassignTask(taskID, userID int, m audit.Meta) error
+	GetAssignees(taskID int) ([]user.User, error)
+	WatchTask(taskID, userID int, m audit.Meta) error
+	UnwatchTask(taskID, userID int, m audit.Meta) error
+	GetWatchers(taskID int) ([]user.User, error)
+	AddLabels(taskID int, labels []string, m audit.Meta) ([]string, error)
+	RemoveLabel(taskID int, label string, m audit.Meta) error
+	GetLabels(userID int) ([]task.Label, error)
+	AddProject(p *project.Project, m audit.Meta) (*project.Project, error)
+	GetProjects(userID int) ([]project.Project, error)
+	GetProjectRole(projectID, userID int) (project.Role, error)
+	AddProjectMember(projectID int, login string, role project.Role, m audit.Meta) (*project.Member, error)
+	GetProjectMembers(projectID int) ([]project.Member, error)
 	InsertUser(data *user.UserData) (int, error)
 	CheckUser(data *user.UserData) (int, error)
+	IsAdmin(userID int) (bool, error)
+	SetCalendarToken(userID int, token string) error
+	DeleteCalendarToken(userID int) error
+	GetCalendarUser(token string) (int, error)
+	GetTaskEvents(taskID, limit int, before *int64) ([]audit.Event, error)
+	GetUserEvents(userID, limit int, before *int64) ([]audit.Event, error)
 }
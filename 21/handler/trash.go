@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"restapi/db"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultTrashRetention = 30 * 24 * time.Hour
+	defaultPurgeInterval  = time.Hour
+	purgeBatchSize        = 500
+)
+
+func envDuration(key string, def time.Duration) (time.Duration, error) {
+	cfg := os.Getenv(key)
+	if cfg == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(cfg)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, cfg)
+	}
+	return d, nil
+}
+
+func (h *Handler) GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	tasks, err := h.DB.GetTrash(userID)
+	if err != nil {
+		log.Printf("Failed to get trash from DB: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to get trash from DB: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	render(w, r, http.StatusOK, tasks)
+}
+
+// RestoreTaskHandler takes a task out of the trash. Only the owner of the task
+// can restore it.
+func (h *Handler) RestoreTaskHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := userIDFromContext(r)
+	if !ok {
+		http.Error(w, "User not found in context", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := h.DB.RestoreTask(id, auditMeta(r, userID))
+	if err != nil {
+		if errors.Is(err, db.ErrTaskNotFound) {
+			http.Error(w, fmt.Sprintf("Task %d not found in trash", id), http.StatusNotFound)
+		} else {
+			log.Printf("Failed to restore task in DB: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to restore task in DB: %v", err), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	for _, t := range tasks {
+		if err = h.Cache.Delete(t.ID); err != nil {
+			log.Printf("Failed to delete from cache: %v", err)
+		}
+	}
+
+	render(w, r, http.StatusOK, tasks)
+}
+
+// PurgeTrash permanently removes tasks that stayed in the trash longer than
+// TrashRetention, checking every PurgeInterval until ctx is done.
+func (h *Handler) PurgeTrash(ctx context.Context) {
+	ticker := time.NewTicker(h.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		h.purgeExpired()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *Handler) purgeExpired() {
+	before := time.Now().Add(-h.TrashRetention)
+	for {
+		ids, keys, err := h.DB.PurgeTasks(before, purgeBatchSize)
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+			return
+		}
+
+		for _, id := range ids {
+			if err = h.Cache.Delete(id); err != nil {
+				log.Printf("Failed to delete from cache: %v", err)
+			}
+		}
+
+		for _, key := range keys {
+			if err = h.Blobs.Delete(key); err != nil {
+				log.Printf("Failed to delete attachment blob %s: %v", key, err)
+			}
+		}
+
+		if len(ids) < purgeBatchSize {
+			return
+		}
+	}
+}
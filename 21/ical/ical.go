@@ -0,0 +1,85 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest content line RFC 5545 allows before folding.
+const maxLineOctets = 75
+
+// Prop is a content line. Name may carry parameters, as in
+// "REFRESH-INTERVAL;VALUE=DURATION", and Value must already be in the format
+// of the property's type; free text goes through Text.
+type Prop struct {
+	Name  string
+	Value string
+}
+
+// Component is a calendar component such as VTODO or VEVENT.
+type Component struct {
+	Name  string
+	Props []Prop
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// Text escapes s as a TEXT value.
+func Text(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// TextList escapes values as a comma-separated list of TEXT values.
+func TextList(values []string) string {
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = Text(v)
+	}
+	return strings.Join(escaped, ",")
+}
+
+// DateTime formats t as a UTC DATE-TIME value.
+func DateTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Encode writes a VCALENDAR object with the given properties and components.
+func Encode(w io.Writer, props []Prop, components []Component) error {
+	bw := bufio.NewWriter(w)
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	for _, p := range props {
+		writeLine(bw, p.Name+":"+p.Value)
+	}
+	for _, c := range components {
+		writeLine(bw, "BEGIN:"+c.Name)
+		for _, p := range c.Props {
+			writeLine(bw, p.Name+":"+p.Value)
+		}
+		writeLine(bw, "END:"+c.Name)
+	}
+	writeLine(bw, "END:VCALENDAR")
+
+	return bw.Flush()
+}
+
+// writeLine folds lines longer than maxLineOctets by continuing them on lines
+// that start with a space, never splitting a UTF-8 sequence.
+func writeLine(bw *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		bw.WriteString(line[:cut])
+		bw.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1
+	}
+	bw.WriteString(line)
+	bw.WriteString("\r\n")
+}
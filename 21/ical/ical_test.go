@@ -0,0 +1,122 @@
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"short", "SUMMARY:Hi", "SUMMARY:Hi\r\n"},
+		{"exactly 75", strings.Repeat("a", 75), strings.Repeat("a", 75) + "\r\n"},
+		{"76", strings.Repeat("a", 76), strings.Repeat("a", 75) + "\r\n a\r\n"},
+		{"three lines", strings.Repeat("a", 200),
+			strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n " + strings.Repeat("a", 51) + "\r\n"},
+		{"no split rune", strings.Repeat("é", 40),
+			strings.Repeat("é", 37) + "\r\n " + strings.Repeat("é", 3) + "\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			bw := bufio.NewWriter(&b)
+			writeLine(bw, tt.line)
+			bw.Flush()
+			if b.String() != tt.want {
+				t.Errorf("got %q, want %q", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestWriteLineUnfolds checks the RFC 5545 rules on mixed widths: no line is
+// longer than 75 octets, every line is valid UTF-8 and unfolding restores
+// the input.
+func TestWriteLineUnfolds(t *testing.T) {
+	for _, line := range []string{
+		"DESCRIPTION:" + strings.Repeat("日本語テキスト", 30),
+		"DESCRIPTION:" + strings.Repeat("a😀", 60),
+		"X:" + strings.Repeat("ab€", 100),
+	} {
+		var b bytes.Buffer
+		bw := bufio.NewWriter(&b)
+		writeLine(bw, line)
+		bw.Flush()
+
+		out := strings.TrimSuffix(b.String(), "\r\n")
+		for _, l := range strings.Split(out, "\r\n") {
+			if len(l) > maxLineOctets {
+				t.Errorf("line of %d octets: %q", len(l), l)
+			}
+			if !utf8.ValidString(l) {
+				t.Errorf("line splits a character: %q", l)
+			}
+		}
+		if unfolded := strings.ReplaceAll(out, "\r\n ", ""); unfolded != line {
+			t.Errorf("unfolded to %q, want %q", unfolded, line)
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain text", "plain text"},
+		{`a,b;c\d`, `a\,b\;c\\d`},
+		{"one\ntwo\r\nthree\rfour", `one\ntwo\nthree\nfour`},
+		{"colon: stays", "colon: stays"},
+	}
+
+	for _, tt := range tests {
+		if got := Text(tt.in); got != tt.want {
+			t.Errorf("Text(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if got, want := TextList([]string{"a,b", "c"}), `a\,b,c`; got != want {
+		t.Errorf("TextList = %q, want %q", got, want)
+	}
+	if got := TextList(nil); got != "" {
+		t.Errorf("TextList(nil) = %q, want empty", got)
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	zone := time.FixedZone("UTC+3", 3*60*60)
+	if got, want := DateTime(time.Date(2026, 1, 2, 1, 4, 5, 0, zone)), "20260101T220405Z"; got != want {
+		t.Errorf("DateTime = %q, want %q", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var b bytes.Buffer
+	err := Encode(&b, []Prop{{Name: "VERSION", Value: "2.0"}}, []Component{
+		{Name: "VTODO", Props: []Prop{{Name: "UID", Value: "task-1@example.com"}, {Name: "SUMMARY", Value: Text("a, b")}}},
+		{Name: "VEVENT"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:task-1@example.com\r\n" +
+		"SUMMARY:a\\, b\r\n" +
+		"END:VTODO\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	if b.String() != want {
+		t.Errorf("got\n%q\nwant\n%q", b.String(), want)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 
+	"restapi/blob"
 	"restapi/cache"
 	"restapi/db"
 	"restapi/handler"
@@ -29,14 +31,23 @@ func main() {
 		log.Fatal(err)
 	}
 
-	h, err := handler.NewHandler(ps, rc)
+	bs, err := blob.NewLocalStore()
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	h, err := handler.NewHandler(ps, rc, bs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go h.PurgeTrash(context.Background())
+
 	r := mux.NewRouter()
+	r.Use(middleware.RequestIDMiddleware)
 	r.HandleFunc("/register", h.RegisterHandler).Methods("POST")
 	r.HandleFunc("/login", h.LoginHandler).Methods("POST")
+	r.HandleFunc("/calendar/{token:[0-9a-f]+}.ics", h.CalendarFeedHandler).Methods("GET")
 
 	api := r.NewRoute().Subrouter()
 	api.Use(middleware.AuthorizationMiddleware)
@@ -44,9 +55,60 @@ func main() {
 	api.HandleFunc("/tasks", h.CreateTaskHandler).Methods("POST")
 	api.HandleFunc("/tasks/{id:[0-9]+}", h.GetTaskHandler).Methods("GET")
 	api.HandleFunc("/tasks", h.GetSelectedTasksHandler).Methods("GET")
+	api.HandleFunc("/tasks:batch", h.BatchTasksHandler).Methods("POST")
+	api.HandleFunc("/tasks/import", h.ImportTasksHandler).Methods("POST")
+	api.HandleFunc("/tasks/export", h.ExportTasksHandler).Methods("GET")
 	api.HandleFunc("/tasks/{id:[0-9]+}", h.UpdateTaskHandler).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}", h.PatchTaskHandler).Methods("PATCH")
 	api.HandleFunc("/tasks/{id:[0-9]+}", h.DeleteTaskHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/revisions", h.GetRevisionsHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/revisions/diff", h.DiffRevisionsHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/revisions/{revision:[0-9]+}/revert", h.RevertTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/subtasks", h.GetSubtasksHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/subtree", h.GetSubtreeHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/progress", h.GetProgressHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/parent", h.MoveTaskHandler).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}/blockers", h.AddBlockerHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/blockers", h.GetBlockersHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/blockers/{blocker_id:[0-9]+}", h.RemoveBlockerHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/blocking", h.GetBlockedHandler).Methods("GET")
 	api.HandleFunc("/tasks/{id:[0-9]+}/comments", h.AddCommentToTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/comments", h.GetCommentsHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/comments/{comment_id:[0-9]+}", h.UpdateCommentHandler).Methods("PUT")
+	api.HandleFunc("/tasks/{id:[0-9]+}/comments/{comment_id:[0-9]+}", h.DeleteCommentHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/attachments", h.UploadAttachmentHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/attachments", h.GetAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/attachments/{attachment_id:[0-9]+}", h.DownloadAttachmentHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/attachments/{attachment_id:[0-9]+}", h.DeleteAttachmentHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/access", h.GrantTaskAccessHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/access/{user_id:[0-9]+}", h.RevokeTaskAccessHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/transitions", h.TransitionTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/transitions", h.GetStatusHistoryHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/assignees", h.AssignTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/assignees", h.GetAssigneesHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/assignees/{user_id:[0-9]+}", h.UnassignTaskHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/watchers", h.WatchTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/watchers", h.GetWatchersHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/watchers", h.UnwatchTaskHandler).Methods("DELETE")
+	api.HandleFunc("/tasks/{id:[0-9]+}/labels", h.AddLabelsHandler).Methods("POST")
+	api.HandleFunc("/tasks/{id:[0-9]+}/labels/{label}", h.RemoveLabelHandler).Methods("DELETE")
+	api.HandleFunc("/labels", h.GetLabelsHandler).Methods("GET")
+	api.HandleFunc("/tasks/my", h.GetMyTasksHandler).Methods("GET")
+	api.HandleFunc("/tasks/trash", h.GetTrashHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/restore", h.RestoreTaskHandler).Methods("POST")
+	api.HandleFunc("/tasks/ready", h.GetReadyTasksHandler).Methods("GET")
+	api.HandleFunc("/tasks/order", h.GetTaskOrderHandler).Methods("GET")
+	api.HandleFunc("/tasks/{id:[0-9]+}/activity", h.GetTaskActivityHandler).Methods("GET")
+	api.HandleFunc("/users/{id:[0-9]+}/activity", h.GetUserActivityHandler).Methods("GET")
+	api.HandleFunc("/workflow", h.GetWorkflowHandler).Methods("GET")
+	api.HandleFunc("/calendar/token", h.CreateCalendarTokenHandler).Methods("POST")
+	api.HandleFunc("/calendar/token", h.DeleteCalendarTokenHandler).Methods("DELETE")
+
+	api.HandleFunc("/projects", h.CreateProjectHandler).Methods("POST")
+	api.HandleFunc("/projects", h.GetProjectsHandler).Methods("GET")
+	api.HandleFunc("/projects/{id:[0-9]+}/members", h.AddProjectMemberHandler).Methods("POST")
+	api.HandleFunc("/projects/{id:[0-9]+}/members", h.GetProjectMembersHandler).Methods("GET")
+	api.HandleFunc("/projects/{id:[0-9]+}/tasks", h.GetProjectTasksHandler).Methods("GET")
 
 	log.Println("Starting server at :8080")
 	log.Fatal(http.ListenAndServe(":8080", r))
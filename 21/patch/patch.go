@@ -0,0 +1,308 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalid means the patch document itself is malformed.
+	ErrInvalid = errors.New("invalid patch")
+	// ErrConflict means a well-formed patch cannot be applied to the document.
+	ErrConflict = errors.New("patch cannot be applied")
+)
+
+// Merge applies a JSON Merge Patch (RFC 7396) to doc.
+func Merge(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %v", err)
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = merge(t[k], v)
+		}
+	}
+	return t
+}
+
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies a JSON Patch (RFC 6902) to doc. Operations are applied in
+// order and the first failing one aborts the whole patch.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %v", err)
+	}
+
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	for i, op := range ops {
+		var err error
+		if root, err = applyOp(root, &op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(root)
+}
+
+func applyOp(root interface{}, op *Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("%w: missing value", ErrInvalid)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+
+		switch op.Op {
+		case "add":
+			return add(root, path, value)
+		case "replace":
+			if _, err := get(root, path); err != nil {
+				return nil, err
+			}
+			return set(root, path, value)
+		default:
+			current, err := get(root, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w: test failed", ErrConflict)
+			}
+			return root, nil
+		}
+	case "remove":
+		return remove(root, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if op.Op == "copy" {
+			return add(root, path, deepCopy(value))
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", ErrConflict)
+		}
+		if root, err = remove(root, from); err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	}
+
+	return nil, fmt.Errorf("%w: unknown operation %q", ErrInvalid, op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalid, p)
+	}
+
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func arrayIndex(token string, n int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return n, nil
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrConflict, token)
+	}
+
+	limit := n - 1
+	if allowEnd {
+		limit = n
+	}
+	if i > limit {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrConflict, i)
+	}
+	return i, nil
+}
+
+func get(root interface{}, path []string) (interface{}, error) {
+	v := root
+	for _, t := range path {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = c[t]; !ok {
+				return nil, fmt.Errorf("%w: member %q not found", ErrConflict, t)
+			}
+		case []interface{}:
+			i, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			v = c[i]
+		default:
+			return nil, fmt.Errorf("%w: cannot descend into %q", ErrConflict, t)
+		}
+	}
+	return v, nil
+}
+
+// set stores value at path, whose parent must exist, and returns the new root.
+func set(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch c := parent.(type) {
+	case map[string]interface{}:
+		c[last] = value
+	case []interface{}:
+		i, err := arrayIndex(last, len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		c[i] = value
+	default:
+		return nil, fmt.Errorf("%w: cannot set %q", ErrConflict, last)
+	}
+	return root, nil
+}
+
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	if c, ok := parent.([]interface{}); ok {
+		i, err := arrayIndex(last, len(c), true)
+		if err != nil {
+			return nil, err
+		}
+		grown := make([]interface{}, 0, len(c)+1)
+		grown = append(append(append(grown, c[:i]...), value), c[i:]...)
+		return set(root, path[:len(path)-1], grown)
+	}
+	return set(root, path, value)
+}
+
+func remove(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrConflict)
+	}
+
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch c := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := c[last]; !ok {
+			return nil, fmt.Errorf("%w: member %q not found", ErrConflict, last)
+		}
+		delete(c, last)
+		return root, nil
+	case []interface{}:
+		i, err := arrayIndex(last, len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		shrunk := append(append(make([]interface{}, 0, len(c)-1), c[:i]...), c[i+1:]...)
+		return set(root, path[:len(path)-1], shrunk)
+	}
+	return nil, fmt.Errorf("%w: cannot remove %q", ErrConflict, last)
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(c))
+		for k, e := range c {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(c))
+		for i, e := range c {
+			a[i] = deepCopy(e)
+		}
+		return a
+	}
+	return v
+}
@@ -0,0 +1,45 @@
+package project
+
+import (
+	"restapi/task"
+	"time"
+)
+
+type Role string
+
+const (
+	RoleOwner  Role = "owner"
+	RoleEditor Role = "editor"
+	RoleViewer Role = "viewer"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleOwner || r == RoleEditor || r == RoleViewer
+}
+
+func (r Role) Access() task.Access {
+	switch r {
+	case RoleOwner:
+		return task.AccessOwner
+	case RoleEditor:
+		return task.AccessWrite
+	case RoleViewer:
+		return task.AccessRead
+	default:
+		return task.AccessNone
+	}
+}
+
+type Project struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Owner     int       `json:"owner"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Member struct {
+	ProjectID int    `json:"project_id"`
+	UserID    int    `json:"user_id"`
+	Login     string `json:"login"`
+	Role      Role   `json:"role"`
+}
@@ -1,20 +1,219 @@
-CREATE TABLE tasks (
-    id SERIAL PRIMARY KEY,
-    name TEXT NOT NULL,
-    description TEXT
-);
-
 CREATE TABLE users (
     id SERIAL PRIMARY KEY,
     login TEXT UNIQUE NOT NULL,
     hash TEXT NOT NULL,
+    is_admin BOOLEAN NOT NULL DEFAULT false,
     created_at TIMESTAMP DEFAULT now()
 );
 
+create table projects (
+    id serial primary key,
+    name text not null,
+    owner int not null references users(id) on delete cascade,
+    created_at timestamp default now()
+);
+
+create table project_members (
+    project_id int not null references projects(id) on delete cascade,
+    user_id int not null references users(id) on delete cascade,
+    role text not null check (role in ('owner', 'editor', 'viewer')),
+    primary key (project_id, user_id)
+);
+
+CREATE TABLE tasks (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    owner INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    project_id INT REFERENCES projects(id) ON DELETE CASCADE,
+    parent_id INT REFERENCES tasks(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'todo',
+    due_at TIMESTAMPTZ,
+    priority INT CHECK (priority >= 0),
+    language REGCONFIG NOT NULL DEFAULT 'russian',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    version INT NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMPTZ,
+    deleted_by INT,
+    deleted_root INT,
+    search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector(language, name), 'A') ||
+        setweight(to_tsvector(language, coalesce(description, '')), 'B')
+    ) STORED
+);
+
+create index tasks_search_vector_idx on tasks using gin (search_vector);
+create index tasks_parent_id_idx on tasks (parent_id);
+create index tasks_deleted_at_idx on tasks (deleted_at) where deleted_at is not null;
+
+create function tasks_version_bump() returns trigger as $$
+begin
+    new.version := old.version + 1;
+    return new;
+end
+$$ language plpgsql;
+
+create trigger tasks_version_bump
+    before update on tasks
+    for each row execute function tasks_version_bump();
+
+create table task_revisions (
+    task_id int not null references tasks(id) on delete cascade,
+    revision int not null,
+    name text not null,
+    description text,
+    author int not null references users(id) on delete cascade,
+    created_at timestamptz not null default now(),
+    primary key (task_id, revision)
+);
+
+create table task_status_history (
+    id serial primary key,
+    task_id int not null references tasks(id) on delete cascade,
+    from_status text not null,
+    to_status text not null,
+    changed_by int not null references users(id) on delete cascade,
+    changed_at timestamp default now()
+);
+
+create table task_access (
+    task_id int not null references tasks(id) on delete cascade,
+    user_id int not null references users(id) on delete cascade,
+    primary key (task_id, user_id)
+);
+
+create table task_assignees (
+    task_id int not null references tasks(id) on delete cascade,
+    user_id int not null references users(id) on delete cascade,
+    assigned_at timestamp default now(),
+    primary key (task_id, user_id)
+);
+
+create table task_watchers (
+    task_id int not null references tasks(id) on delete cascade,
+    user_id int not null references users(id) on delete cascade,
+    primary key (task_id, user_id)
+);
+
+create table task_dependencies (
+    task_id int not null references tasks(id) on delete cascade,
+    blocker_id int not null references tasks(id) on delete cascade,
+    created_at timestamp default now(),
+    primary key (task_id, blocker_id),
+    check (task_id <> blocker_id)
+);
+
+create index task_dependencies_blocker_id_idx on task_dependencies (blocker_id);
+
+create table labels (
+    id serial primary key,
+    name text unique not null
+);
+
+create table task_labels (
+    task_id int not null references tasks(id) on delete cascade,
+    label_id int not null references labels(id) on delete cascade,
+    primary key (task_id, label_id)
+);
+
+create table attachments (
+    id serial primary key,
+    task_id int not null references tasks(id) on delete cascade,
+    uploader int not null references users(id) on delete cascade,
+    filename text not null,
+    content_type text not null,
+    size bigint not null,
+    storage_key text unique not null,
+    created_at timestamptz not null default now()
+);
+
 create table comments (
     id serial primary key,
     task_id int not null references tasks(id) on delete cascade,
     author int not null references users(id) on delete cascade,
+    parent_id int references comments(id) on delete cascade,
     text text not null,
-    created_at timestamp default now()
-);
\ No newline at end of file
+    created_at timestamp default now(),
+    edited_at timestamp,
+    deleted_at timestamp,
+    search_vector tsvector
+);
+
+create function comment_tree(p_task_id int, p_parent_id int) returns json as $$
+begin
+    return (
+        select coalesce(json_agg(
+            json_build_object(
+                'id', c.id,
+                'parent_id', c.parent_id,
+                'author', c.author,
+                'author_login', (select u.login from users u where u.id = c.author),
+                'text', c.text,
+                'created_at', c.created_at,
+                'edited_at', c.edited_at,
+                'deleted', c.deleted_at is not null,
+                'replies', comment_tree(p_task_id, c.id)
+            ) order by c.created_at, c.id
+        ), '[]'::json)
+        from comments c
+        where c.task_id = p_task_id and c.parent_id is not distinct from p_parent_id
+    );
+end
+$$ language plpgsql stable;
+
+create index comments_search_vector_idx on comments using gin (search_vector);
+
+create function comments_search_vector() returns trigger as $$
+begin
+    new.search_vector := to_tsvector((select language from tasks where id = new.task_id), new.text);
+    return new;
+end
+$$ language plpgsql;
+
+create trigger comments_search_vector_update
+    before insert or update of text on comments
+    for each row execute function comments_search_vector();
+
+create function tasks_language_update() returns trigger as $$
+begin
+    update comments set search_vector = to_tsvector(new.language, text) where task_id = new.id;
+    return null;
+end
+$$ language plpgsql;
+
+create trigger tasks_language_update
+    after update of language on tasks
+    for each row when (old.language is distinct from new.language)
+    execute function tasks_language_update();
+
+create table audit_events (
+    id bigserial primary key,
+    actor int not null,
+    action text not null,
+    target_type text not null,
+    target_id int not null,
+    task_id int,
+    before jsonb,
+    after jsonb,
+    request_id text,
+    created_at timestamptz not null default now()
+);
+
+create index audit_events_task_id_idx on audit_events (task_id, id);
+create index audit_events_actor_idx on audit_events (actor, id);
+
+create function audit_events_append_only() returns trigger as $$
+begin
+    raise exception 'audit_events is append-only';
+end
+$$ language plpgsql;
+
+create trigger audit_events_append_only
+    before update or delete on audit_events
+    for each row execute function audit_events_append_only();
+
+create table calendar_tokens (
+    user_id int primary key references users(id) on delete cascade,
+    token_hash text not null unique,
+    created_at timestamptz not null default now()
+);
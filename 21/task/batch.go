@@ -0,0 +1,30 @@
+package task
+
+type BatchOpKind string
+
+const (
+	BatchCreate BatchOpKind = "create"
+	BatchUpdate BatchOpKind = "update"
+	BatchDelete BatchOpKind = "delete"
+)
+
+// BatchOp is one item of a bulk request. Task is used by create and update,
+// ID by update and delete, and Mode by delete. Labels are attached to a
+// created task. A non-nil IfMatch is checked against the task version like
+// the If-Match header.
+type BatchOp struct {
+	Op      BatchOpKind
+	ID      int
+	Task    *Task
+	Labels  []string
+	Mode    DeleteMode
+	IfMatch []int
+}
+
+// BatchOutcome is the result of one BatchOp. Touched lists the IDs of all
+// tasks the operation changed.
+type BatchOutcome struct {
+	Task    *Task
+	Touched []int
+	Err     error
+}
@@ -0,0 +1,71 @@
+package task
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrDependencyCycle = errors.New("dependencies form a cycle")
+
+// Dependency means that TaskID cannot be finished before BlockerID.
+type Dependency struct {
+	TaskID    int       `json:"task_id"`
+	BlockerID int       `json:"blocker_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TopologicalOrder sorts tasks so that every blocker comes before the tasks it
+// blocks. Dependencies on tasks outside the set are ignored and independent
+// tasks keep their relative input order.
+func TopologicalOrder(tasks []Task, deps []Dependency) ([]Task, error) {
+	index := make(map[int]int, len(tasks))
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+
+	blocked := make([][]int, len(tasks))
+	pending := make([]int, len(tasks))
+	for _, d := range deps {
+		ti, ok := index[d.TaskID]
+		if !ok {
+			continue
+		}
+		bi, ok := index[d.BlockerID]
+		if !ok {
+			continue
+		}
+		blocked[bi] = append(blocked[bi], ti)
+		pending[ti]++
+	}
+
+	var ready []int
+	for i := range tasks {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	ordered := make([]Task, 0, len(tasks))
+	for len(ready) > 0 {
+		next := 0
+		for j := range ready {
+			if ready[j] < ready[next] {
+				next = j
+			}
+		}
+		i := ready[next]
+		ready = append(ready[:next], ready[next+1:]...)
+
+		ordered = append(ordered, tasks[i])
+		for _, ti := range blocked[i] {
+			if pending[ti]--; pending[ti] == 0 {
+				ready = append(ready, ti)
+			}
+		}
+	}
+
+	if len(ordered) != len(tasks) {
+		return nil, ErrDependencyCycle
+	}
+	return ordered, nil
+}
@@ -0,0 +1,60 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTopologicalOrder(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []int
+		deps [][2]int // task, blocker
+		want []int
+		err  error
+	}{
+		{"no tasks", nil, nil, []int{}, nil},
+		{"independent keep order", []int{3, 1, 2}, nil, []int{3, 1, 2}, nil},
+		{"blocker first", []int{1, 2}, [][2]int{{1, 2}}, []int{2, 1}, nil},
+		{"chain", []int{1, 2, 3}, [][2]int{{1, 2}, {2, 3}}, []int{3, 2, 1}, nil},
+		{"diamond", []int{1, 2, 3, 4}, [][2]int{{1, 2}, {1, 3}, {2, 4}, {3, 4}}, []int{4, 2, 3, 1}, nil},
+		{"released task keeps input rank", []int{1, 2, 3}, [][2]int{{1, 3}}, []int{2, 3, 1}, nil},
+		{"outside blocker ignored", []int{1, 2}, [][2]int{{1, 9}, {9, 2}}, []int{1, 2}, nil},
+		{"self cycle", []int{1}, [][2]int{{1, 1}}, nil, ErrDependencyCycle},
+		{"cycle", []int{1, 2, 3}, [][2]int{{1, 2}, {2, 3}, {3, 1}}, nil, ErrDependencyCycle},
+		{"cycle beside free task", []int{1, 2, 3}, [][2]int{{2, 3}, {3, 2}}, nil, ErrDependencyCycle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks := make([]Task, len(tt.ids))
+			for i, id := range tt.ids {
+				tasks[i].ID = id
+			}
+			deps := make([]Dependency, len(tt.deps))
+			for i, d := range tt.deps {
+				deps[i] = Dependency{TaskID: d[0], BlockerID: d[1]}
+			}
+
+			ordered, err := TopologicalOrder(tasks, deps)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("got error %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]int, len(ordered))
+			for i, o := range ordered {
+				got[i] = o.ID
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got order %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package task
+
+import "fmt"
+
+// DeleteMode decides what happens to the subtasks of a deleted task.
+type DeleteMode string
+
+const (
+	DeleteCascade DeleteMode = "cascade"
+	DeleteOrphan  DeleteMode = "orphan"
+	DeleteForbid  DeleteMode = "forbid"
+)
+
+func ParseDeleteMode(s string) (DeleteMode, error) {
+	switch m := DeleteMode(s); m {
+	case DeleteCascade, DeleteOrphan, DeleteForbid:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown delete mode %q, allowed: cascade, orphan, forbid", s)
+}
+
+// Progress is rolled up over all descendants of a task. A task without
+// subtasks reports its own status.
+type Progress struct {
+	TaskID  int     `json:"task_id"`
+	Total   int     `json:"total"`
+	Closed  int     `json:"closed"`
+	Percent float64 `json:"percent"`
+}
+
+type Subtask struct {
+	Task
+	Depth int `json:"depth"`
+}
@@ -0,0 +1,172 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Revision is a saved version of the name and description of a task.
+type Revision struct {
+	TaskID      int       `json:"task_id"`
+	Revision    int       `json:"revision"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Author      int       `json:"author"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = " "
+	DiffInsert DiffOp = "+"
+	DiffDelete DiffOp = "-"
+)
+
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+type RevisionDiff struct {
+	From        int        `json:"from"`
+	To          int        `json:"to"`
+	Name        []DiffLine `json:"name"`
+	Description []DiffLine `json:"description"`
+}
+
+// MaxDiffLines caps the number of lines LineDiff compares, since the time it
+// takes grows with the product of the input size and the edit distance.
+const MaxDiffLines = 10000
+
+var ErrDiffTooLarge = errors.New("texts are too large to diff")
+
+func DiffRevisions(from, to *Revision) (*RevisionDiff, error) {
+	name, err := LineDiff(from.Name, to.Name)
+	if err != nil {
+		return nil, err
+	}
+	description, err := LineDiff(from.Description, to.Description)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevisionDiff{
+		From:        from.Revision,
+		To:          to.Revision,
+		Name:        name,
+		Description: description,
+	}, nil
+}
+
+// LineDiff returns the shortest edit script turning a into b line by line,
+// using the linear space variant of the Myers algorithm. It fails with
+// ErrDiffTooLarge when a and b have more than MaxDiffLines lines together.
+func LineDiff(a, b string) ([]DiffLine, error) {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(x)+len(y) > MaxDiffLines {
+		return nil, ErrDiffTooLarge
+	}
+
+	lines := make([]DiffLine, 0, len(x)+len(y))
+	diffLines(x, y, &lines)
+	return lines, nil
+}
+
+// diffLines appends the edit script for x and y to out. After trimming the
+// common prefix and suffix it splits the problem at the middle snake, so both
+// halves need fewer edits and the recursion ends.
+func diffLines(x, y []string, out *[]DiffLine) {
+	prefix := 0
+	for prefix < len(x) && prefix < len(y) && x[prefix] == y[prefix] {
+		prefix++
+	}
+	appendLines(out, DiffEqual, x[:prefix])
+	x, y = x[prefix:], y[prefix:]
+
+	suffix := 0
+	for suffix < len(x) && suffix < len(y) && x[len(x)-1-suffix] == y[len(y)-1-suffix] {
+		suffix++
+	}
+	common := x[len(x)-suffix:]
+	x, y = x[:len(x)-suffix], y[:len(y)-suffix]
+
+	switch {
+	case len(x) == 0:
+		appendLines(out, DiffInsert, y)
+	case len(y) == 0:
+		appendLines(out, DiffDelete, x)
+	default:
+		xs, ys, xe, ye := middleSnake(x, y)
+		diffLines(x[:xs], y[:ys], out)
+		appendLines(out, DiffEqual, x[xs:xe])
+		diffLines(x[xe:], y[ye:], out)
+	}
+
+	appendLines(out, DiffEqual, common)
+}
+
+func appendLines(out *[]DiffLine, op DiffOp, lines []string) {
+	for _, l := range lines {
+		*out = append(*out, DiffLine{Op: op, Text: l})
+	}
+}
+
+// middleSnake runs the Myers search from both ends at once until the paths
+// overlap and returns the start and end of the snake where they met. Only the
+// current furthest points of each diagonal are kept, in O(len(x)+len(y))
+// space. Backward points are stored as distances from the ends of x and y.
+func middleSnake(x, y []string) (xs, ys, xe, ye int) {
+	n, m := len(x), len(y)
+	delta := n - m
+	odd := delta%2 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+
+	forward := make([]int, 2*max+3)
+	backward := make([]int, 2*max+3)
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || (k != d && forward[off+k-1] < forward[off+k+1]) {
+				i = forward[off+k+1]
+			} else {
+				i = forward[off+k-1] + 1
+			}
+			j := i - k
+			si, sj := i, j
+			for i < n && j < m && x[i] == y[j] {
+				i++
+				j++
+			}
+			forward[off+k] = i
+
+			if c := delta - k; odd && c >= -(d-1) && c <= d-1 && i+backward[off+c] >= n {
+				return si, sj, i, j
+			}
+		}
+
+		for c := -d; c <= d; c += 2 {
+			var i int
+			if c == -d || (c != d && backward[off+c-1] < backward[off+c+1]) {
+				i = backward[off+c+1]
+			} else {
+				i = backward[off+c-1] + 1
+			}
+			j := i - c
+			si, sj := i, j
+			for i < n && j < m && x[n-1-i] == y[m-1-j] {
+				i++
+				j++
+			}
+			backward[off+c] = i
+
+			if k := delta - c; !odd && k >= -d && k <= d && forward[off+k]+i >= n {
+				return n - i, m - j, n - si, m - sj
+			}
+		}
+	}
+
+	panic("middle snake not found")
+}
@@ -0,0 +1,114 @@
+package task
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func diffText(lines []DiffLine) string {
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(string(l.Op) + l.Text + "\n")
+	}
+	return b.String()
+}
+
+func TestLineDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []DiffLine
+	}{
+		{"equal", "a\nb", "a\nb", []DiffLine{{DiffEqual, "a"}, {DiffEqual, "b"}}},
+		{"insert", "a\nc", "a\nb\nc", []DiffLine{{DiffEqual, "a"}, {DiffInsert, "b"}, {DiffEqual, "c"}}},
+		{"delete", "a\nb\nc", "a\nc", []DiffLine{{DiffEqual, "a"}, {DiffDelete, "b"}, {DiffEqual, "c"}}},
+		{"replace", "a", "b", []DiffLine{{DiffDelete, "a"}, {DiffInsert, "b"}}},
+		{"empty to text", "", "a", []DiffLine{{DiffDelete, ""}, {DiffInsert, "a"}}},
+		{"append", "a", "a\nb", []DiffLine{{DiffEqual, "a"}, {DiffInsert, "b"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LineDiff(tt.a, tt.b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LineDiff(%q, %q) =\n%s\nwant\n%s", tt.a, tt.b, diffText(got), diffText(tt.want))
+			}
+		})
+	}
+}
+
+// lcsLength is the reference the edit scripts are checked against: a
+// shortest script keeps exactly the longest common subsequence.
+func lcsLength(x, y []string) int {
+	prev := make([]int, len(y)+1)
+	cur := make([]int, len(y)+1)
+	for i := range x {
+		for j := range y {
+			switch {
+			case x[i] == y[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(y)]
+}
+
+func TestLineDiffIsShortest(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomText := func() string {
+		lines := make([]string, rnd.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rnd.Intn(4)))
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	for n := 0; n < 500; n++ {
+		a, b := randomText(), randomText()
+		got, err := LineDiff(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var from, to []string
+		equal := 0
+		for _, l := range got {
+			if l.Op != DiffInsert {
+				from = append(from, l.Text)
+			}
+			if l.Op != DiffDelete {
+				to = append(to, l.Text)
+			}
+			if l.Op == DiffEqual {
+				equal++
+			}
+		}
+		if strings.Join(from, "\n") != a || strings.Join(to, "\n") != b {
+			t.Fatalf("LineDiff(%q, %q) does not rebuild its inputs:\n%s", a, b, diffText(got))
+		}
+		if want := lcsLength(strings.Split(a, "\n"), strings.Split(b, "\n")); equal != want {
+			t.Fatalf("LineDiff(%q, %q) keeps %d lines, want %d", a, b, equal, want)
+		}
+	}
+}
+
+func TestLineDiffTooLarge(t *testing.T) {
+	a := strings.Repeat("x\n", MaxDiffLines/2)
+	if _, err := LineDiff(a, a+"y"); !errors.Is(err, ErrDiffTooLarge) {
+		t.Errorf("got %v, want ErrDiffTooLarge", err)
+	}
+	if _, err := LineDiff(a[:len(a)-2], a[:len(a)-2]); err != nil {
+		t.Errorf("got %v for inputs within the limit", err)
+	}
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+var SortFields = []string{
+	"id", "name", "description", "status", "priority", "due_at", "created_at", "comment_count", "rank",
+}
+
+type SortKey struct {
+	Field      string
+	Desc       bool
+	NullsFirst bool
+}
+
+func (k SortKey) String() string {
+	s := k.Field
+	if k.Desc {
+		s = "-" + s
+	}
+	if k.NullsFirst {
+		s += ":nulls_first"
+	}
+	return s
+}
+
+// ParseSort reads a list such as "-priority:nulls_first,created_at,id".
+// A leading "-" sorts the key descending, desc flips the default direction.
+func ParseSort(s string, desc bool) ([]SortKey, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		k := SortKey{Desc: desc}
+
+		field, nulls, _ := strings.Cut(strings.TrimSpace(part), ":")
+		if strings.HasPrefix(field, "-") {
+			field = field[1:]
+			k.Desc = true
+		} else if strings.HasPrefix(field, "+") {
+			field = field[1:]
+			k.Desc = false
+		}
+
+		switch nulls {
+		case "", "nulls_last":
+		case "nulls_first":
+			k.NullsFirst = true
+		default:
+			return nil, fmt.Errorf("invalid null ordering %q for %s, expected nulls_first or nulls_last", nulls, field)
+		}
+
+		if !validSortField(field) {
+			return nil, fmt.Errorf("unknown sort field %q, allowed: %s", field, strings.Join(SortFields, ", "))
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field)
+		}
+		seen[field] = true
+
+		k.Field = field
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+func validSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
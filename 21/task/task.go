@@ -2,20 +2,122 @@ package task
 
 import (
 	"encoding/json"
+	"restapi/filter"
 	"time"
 )
 
+// MaxDescriptionLength is the longest description in bytes a task may have.
+const MaxDescriptionLength = 20000
+
 type Task struct {
-	ID          int             `json:"id"`
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Comments    json.RawMessage `json:"comments"`
+	ID           int             `json:"id"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	Owner        int             `json:"owner"`
+	ProjectID    *int            `json:"project_id,omitempty"`
+	ParentID     *int            `json:"parent_id,omitempty"`
+	Status       Status          `json:"status"`
+	DueAt        *time.Time      `json:"due_at,omitempty"`
+	Priority     *int            `json:"priority,omitempty"`
+	Labels       []string        `json:"labels"`
+	Language     string          `json:"language,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	Version      int             `json:"version"`
+	CommentCount int             `json:"comment_count"`
+	Rank         *float64        `json:"rank,omitempty"`
+	Snippet      *string         `json:"snippet,omitempty"`
+	Comments     json.RawMessage `json:"comments,omitempty"`
 }
 
 type Comment struct {
-	ID        int       `json:"id"`
-	TaskID    int       `json:"task_id"`
-	Author    int       `json:"author"`
-	Text      string    `json:"text"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          int        `json:"id"`
+	TaskID      int        `json:"task_id"`
+	ParentID    *int       `json:"parent_id,omitempty"`
+	Author      int        `json:"author"`
+	AuthorLogin string     `json:"author_login,omitempty"`
+	Text        string     `json:"text"`
+	CreatedAt   time.Time  `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
+	Deleted     bool       `json:"deleted,omitempty"`
+}
+
+type Attachment struct {
+	ID          int       `json:"id"`
+	TaskID      int       `json:"task_id"`
+	Uploader    int       `json:"uploader"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Filter struct {
+	UserID         int
+	Name           string
+	ProjectID      *int
+	Statuses       []Status
+	AssigneeID     *int
+	DueBefore      *time.Time
+	DueAfter       *time.Time
+	HasDue         bool
+	Overdue        bool
+	Ready          bool
+	Closed         []Status
+	MinPriority    *int
+	LabelsAny      []string
+	LabelsAll      []string
+	Search         string
+	SearchLanguage string
+	Expr           filter.Expr
+	Sort           []SortKey
+	Limit          *int
+	Cursor         string
+	LatestComments *int
+	WithTotal      bool
 }
+
+// TrashedTask is a deleted task waiting in the trash. Subtasks counts the
+// subtasks that were trashed with it and will be restored with it.
+type TrashedTask struct {
+	Task
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy int       `json:"deleted_by"`
+	Subtasks  int       `json:"subtasks"`
+}
+
+type Page struct {
+	Tasks      []Task
+	NextCursor string
+	PrevCursor string
+	Total      *int
+}
+
+// Stamp summarizes a set of tasks. Any edit bumps a task's version, so the
+// stamp changes whenever a task joins, leaves or changes within the set.
+type Stamp struct {
+	Count  int
+	Digest string
+}
+
+const DefaultLanguage = "russian"
+
+var Languages = map[string]bool{
+	"russian": true,
+	"english": true,
+	"simple":  true,
+}
+
+type Label struct {
+	Name      string `json:"name"`
+	TaskCount int    `json:"task_count"`
+}
+
+type Access int
+
+const (
+	AccessNone Access = iota
+	AccessRead
+	AccessWrite
+	AccessOwner
+)
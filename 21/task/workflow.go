@@ -0,0 +1,96 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in_progress"
+	StatusReview     Status = "review"
+	StatusDone       Status = "done"
+)
+
+type StatusChange struct {
+	ID        int       `json:"id"`
+	TaskID    int       `json:"task_id"`
+	From      Status    `json:"from"`
+	To        Status    `json:"to"`
+	ChangedBy int       `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+type Workflow struct {
+	Initial     Status              `json:"initial"`
+	Closed      []Status            `json:"closed"`
+	Transitions map[Status][]Status `json:"transitions"`
+}
+
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Initial: StatusTodo,
+		Closed:  []Status{StatusDone},
+		Transitions: map[Status][]Status{
+			StatusTodo:       {StatusInProgress},
+			StatusInProgress: {StatusReview, StatusTodo},
+			StatusReview:     {StatusDone, StatusInProgress},
+			StatusDone:       {StatusTodo},
+		},
+	}
+}
+
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	var wf Workflow
+	if err := json.Unmarshal(data, &wf); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %v", err)
+	}
+
+	if !wf.Known(wf.Initial) {
+		return nil, fmt.Errorf("initial status %q has no transitions", wf.Initial)
+	}
+	for _, s := range wf.Closed {
+		if !wf.Known(s) {
+			return nil, fmt.Errorf("closed status %q has no transitions", s)
+		}
+	}
+	for from, targets := range wf.Transitions {
+		for _, to := range targets {
+			if !wf.Known(to) {
+				return nil, fmt.Errorf("status %q reachable from %q has no transitions", to, from)
+			}
+		}
+	}
+
+	return &wf, nil
+}
+
+func (wf *Workflow) Known(s Status) bool {
+	_, ok := wf.Transitions[s]
+	return ok
+}
+
+func (wf *Workflow) Allowed(from Status) []Status {
+	return wf.Transitions[from]
+}
+
+func (wf *Workflow) CanTransition(from, to Status) bool {
+	for _, s := range wf.Transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (wf *Workflow) IsClosed(s Status) bool {
+	for _, c := range wf.Closed {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,10 @@
 package user
 
+type User struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+}
+
 type UserData struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`